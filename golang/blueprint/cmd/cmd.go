@@ -8,6 +8,7 @@ import (
 	"github.com/synkube/app/blueprint/data"
 	"github.com/synkube/app/core/common"
 	coreData "github.com/synkube/app/core/data"
+	"github.com/synkube/app/core/ginhelper"
 	"github.com/urfave/cli/v2"
 )
 
@@ -61,6 +62,8 @@ func runApplication(c *cli.Context) error {
 
 	ds = data.Initialize(&cfg)
 	dm := data.NewDataModel(ds)
-	StartServers(cfg.ServerConfig, dm)
+	health := ginhelper.NewHealthChecker()
+	health.AddReadinessCheck("database", ds.Ping)
+	StartServers(cfg.ServerConfig, dm, health)
 	return nil
 }
@@ -15,11 +15,11 @@ import (
 	"github.com/synkube/app/core/ginhelper"
 )
 
-func StartServers(servers []coreData.ServerConfig, dm *data.DataModel) {
+func StartServers(servers []coreData.ServerConfig, dm *data.DataModel, health *ginhelper.HealthChecker) {
 	for _, server := range servers {
 		switch server.Type {
 		case "http":
-			go startHTTPServer(server)
+			go startHTTPServer(server, health)
 		case "grpc":
 			// Add gRPC server initialization here
 		case "graphql":
@@ -35,9 +35,10 @@ func StartServers(servers []coreData.ServerConfig, dm *data.DataModel) {
 	select {}
 }
 
-func startHTTPServer(cfg coreData.ServerConfig) {
+func startHTTPServer(cfg coreData.ServerConfig, health *ginhelper.HealthChecker) {
 	addr := fmt.Sprintf("localhost:%d", cfg.Port)
-	r := ginhelper.New([]string{ginhelper.HealthCheckRoute, ginhelper.RobotsTxtRoute})
+	r := ginhelper.New([]string{ginhelper.RobotsTxtRoute})
+	health.Register(r)
 
 	r.GET("/", func(c *gin.Context) {
 		c.String(http.StatusOK, "Hello, World!")
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -30,12 +31,7 @@ func (s *DataStore) Clean(models ...interface{}) error {
 }
 
 func (s *DataStore) CheckConnection() error {
-	sqlDB, err := s.db.DB()
-	if err != nil {
-		return err
-	}
-
-	if err = sqlDB.Ping(); err != nil {
+	if err := s.Ping(context.Background()); err != nil {
 		return err
 	} else {
 		log.Println("Database connection is OK")
@@ -43,6 +39,15 @@ func (s *DataStore) CheckConnection() error {
 	}
 }
 
+// Ping checks the database connection without logging, for health checks
+func (s *DataStore) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
 func NewDataStore(cfg DbConfig) *DataStore {
 	ds := InitializeDBFromConfig(cfg)
 	err := ds.CheckConnection()
@@ -0,0 +1,146 @@
+package ginhelper
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	Livez  = "/livez"
+	Readyz = "/readyz"
+
+	// DefaultHealthCheckTimeout bounds the time all checks of a probe may take
+	DefaultHealthCheckTimeout = 5 * time.Second
+)
+
+// Check reports an error when a dependency is not healthy
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of a single check in a probe response
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthReport is the response of a probe
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// HealthChecker is a registry of named checks served by /livez and /readyz. A probe is UP when
+// all its checks pass, otherwise it responds with 503 and the error of each failing check.
+type HealthChecker struct {
+	mutex     sync.RWMutex
+	liveness  map[string]Check
+	readiness map[string]Check
+	timeout   time.Duration
+}
+
+// NewHealthChecker creates a HealthChecker without checks
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{
+		liveness:  make(map[string]Check),
+		readiness: make(map[string]Check),
+		timeout:   DefaultHealthCheckTimeout,
+	}
+}
+
+// SetTimeout changes the time all checks of a probe may take
+func (h *HealthChecker) SetTimeout(timeout time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.timeout = timeout
+}
+
+// AddLivenessCheck adds a check to /livez, replacing a check of the same name. Liveness checks
+// should only fail when the process needs a restart.
+func (h *HealthChecker) AddLivenessCheck(name string, check Check) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.liveness[name] = check
+}
+
+// AddReadinessCheck adds a check to /readyz, replacing a check of the same name
+func (h *HealthChecker) AddReadinessCheck(name string, check Check) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.readiness[name] = check
+}
+
+// Register serves /livez and /readyz on r, and the liveness checks on /health for older probes.
+// The engine must not serve HealthCheckRoute itself.
+func (h *HealthChecker) Register(r gin.IRoutes) {
+	r.GET(Livez, func(c *gin.Context) {
+		h.serve(c, h.liveness)
+	})
+	r.GET(HealthCheck, func(c *gin.Context) {
+		h.serve(c, h.liveness)
+	})
+	r.GET(Readyz, func(c *gin.Context) {
+		h.serve(c, h.readiness)
+	})
+}
+
+func (h *HealthChecker) serve(c *gin.Context, checks map[string]Check) {
+	report := h.run(c.Request.Context(), checks)
+	code := http.StatusOK
+	if report.Status != "UP" {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, report)
+}
+
+// run runs checks concurrently and reports their outcome
+func (h *HealthChecker) run(ctx context.Context, checks map[string]Check) HealthReport {
+	h.mutex.RLock()
+	timeout := h.timeout
+	pending := make(map[string]Check, len(checks))
+	for name, check := range checks {
+		pending[name] = check
+	}
+	h.mutex.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	report := HealthReport{Status: "UP", Checks: make(map[string]CheckResult, len(pending))}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range pending {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			result := CheckResult{Status: "UP"}
+			if err := runCheck(ctx, check); err != nil {
+				result = CheckResult{Status: "DOWN", Error: err.Error()}
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			report.Checks[name] = result
+			if result.Status != "UP" {
+				report.Status = "DOWN"
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+// runCheck runs a check, giving up once ctx is done even if the check ignores it
+func runCheck(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
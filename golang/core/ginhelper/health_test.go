@@ -0,0 +1,130 @@
+package ginhelper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// probe serves the checks of h and requests path
+func probe(t *testing.T, h *HealthChecker, path string) (int, HealthReport) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h.Register(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json; charset=utf-8" {
+		t.Errorf("content type %q, want JSON", contentType)
+	}
+	var report HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid body %q: %v", w.Body.String(), err)
+	}
+	return w.Code, report
+}
+
+func passing(context.Context) error { return nil }
+
+func TestProbesPass(t *testing.T) {
+	h := NewHealthChecker()
+	h.AddLivenessCheck("process", passing)
+	h.AddReadinessCheck("database", passing)
+	h.AddReadinessCheck("rpc", passing)
+
+	for path, checks := range map[string][]string{Livez: {"process"}, HealthCheck: {"process"}, Readyz: {"database", "rpc"}} {
+		code, report := probe(t, h, path)
+		if code != http.StatusOK || report.Status != "UP" {
+			t.Errorf("%s = %d %s, want 200 UP", path, code, report.Status)
+		}
+		want := make(map[string]CheckResult)
+		for _, name := range checks {
+			want[name] = CheckResult{Status: "UP"}
+		}
+		if !reflect.DeepEqual(report.Checks, want) {
+			t.Errorf("%s checks = %v, want %v", path, report.Checks, want)
+		}
+	}
+}
+
+func TestProbeWithoutChecksPasses(t *testing.T) {
+	code, report := probe(t, NewHealthChecker(), Readyz)
+	if code != http.StatusOK || report.Status != "UP" || len(report.Checks) != 0 {
+		t.Errorf("%s without checks = %d %+v, want 200 UP without checks", Readyz, code, report)
+	}
+}
+
+func TestFailingCheckTurnsProbeDown(t *testing.T) {
+	h := NewHealthChecker()
+	h.AddLivenessCheck("process", passing)
+	h.AddReadinessCheck("database", passing)
+	h.AddReadinessCheck("rpc", func(context.Context) error { return errors.New("connection refused") })
+
+	code, report := probe(t, h, Readyz)
+	if code != http.StatusServiceUnavailable || report.Status != "DOWN" {
+		t.Errorf("%s = %d %s, want 503 DOWN", Readyz, code, report.Status)
+	}
+	want := map[string]CheckResult{
+		"database": {Status: "UP"},
+		"rpc":      {Status: "DOWN", Error: "connection refused"},
+	}
+	if !reflect.DeepEqual(report.Checks, want) {
+		t.Errorf("checks = %v, want %v", report.Checks, want)
+	}
+
+	// Readiness checks leave liveness alone
+	if code, report := probe(t, h, Livez); code != http.StatusOK || report.Status != "UP" {
+		t.Errorf("%s = %d %s with a failing readiness check, want 200 UP", Livez, code, report.Status)
+	}
+}
+
+func TestSlowCheckTimesOut(t *testing.T) {
+	h := NewHealthChecker()
+	h.SetTimeout(20 * time.Millisecond)
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+	// The check ignores its context, the probe answers anyway
+	h.AddReadinessCheck("rpc", func(context.Context) error {
+		<-block
+		return nil
+	})
+	h.AddReadinessCheck("database", passing)
+
+	start := time.Now()
+	code, report := probe(t, h, Readyz)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("probe took %s with a timeout of 20ms", elapsed)
+	}
+	if code != http.StatusServiceUnavailable || report.Status != "DOWN" {
+		t.Errorf("%s = %d %s, want 503 DOWN", Readyz, code, report.Status)
+	}
+	if result := report.Checks["rpc"]; result.Status != "DOWN" || result.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("slow check = %+v, want DOWN with %q", result, context.DeadlineExceeded)
+	}
+	if result := report.Checks["database"]; result.Status != "UP" {
+		t.Errorf("fast check = %+v, want UP", result)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	report := HealthReport{Status: "DOWN", Checks: map[string]CheckResult{
+		"database": {Status: "UP"},
+		"rpc:1":    {Status: "DOWN", Error: "timeout"},
+	}}
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"status":"DOWN","checks":{"database":{"status":"UP"},"rpc:1":{"status":"DOWN","error":"timeout"}}}`
+	if string(encoded) != want {
+		t.Errorf("JSON = %s, want %s", encoded, want)
+	}
+}
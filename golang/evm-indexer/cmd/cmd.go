@@ -1,19 +1,26 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/synkube/app/core/common"
 	coreData "github.com/synkube/app/core/data"
+	"github.com/synkube/app/core/ginhelper"
 	"github.com/synkube/app/evm-indexer/config"
 	"github.com/synkube/app/evm-indexer/data"
 	"github.com/synkube/app/evm-indexer/indexer"
 	"github.com/urfave/cli/v2"
 )
 
+// defaultShutdownTimeout is the time the servers get to finish their requests on shutdown
+const defaultShutdownTimeout = 10 * time.Second
+
 var cfg config.Config
 var ds *coreData.DataStore
 
@@ -41,6 +48,13 @@ func Start(args []string, buildInfo common.BuildInfo) error {
 					return runServer(c)
 				},
 			},
+			{
+				Name:  "retry-failed",
+				Usage: "Index the blocks recorded as failed again",
+				Action: func(c *cli.Context) error {
+					return runRetryFailed(c)
+				},
+			},
 			{
 				Name:  "info",
 				Usage: "Information about how to use this application",
@@ -64,43 +78,94 @@ func Start(args []string, buildInfo common.BuildInfo) error {
 }
 
 func runApplication(c *cli.Context) error {
-	// ctx, cancel := context.WithCancel(context.Background())
 	if err := config.InitConfig(c.String("config"), &cfg); err != nil {
 		return err
 	}
 	log.Println("Running the application with arguments:", c.Args().Slice())
 
+	ctx, stop := signalContext()
+	defer stop()
+
 	ds = data.Initialize(&cfg)
-	bds := data.NewBlockchainDataStore(ds)
-	go StartServers(cfg.ServerConfig, bds)
-	indexer.StartIndexing(cfg.Chain, bds, cfg.Indexer)
+	bds := data.NewBlockchainDataStore(ds, cfg.Indexer.BatchSize)
+	health := newHealthChecker(ctx)
+	shutdown := StartServers(cfg.ServerConfig, bds, health)
+	if err := indexer.StartIndexingChains(ctx, cfg.IndexedChains(), bds, cfg.Indexer, cfg.Contracts, health); err != nil {
+		log.Printf("Indexing failed: %v", err)
+	}
 
-	// Wait for an interrupt signal to gracefully shut down the server
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	// Keep serving until an interrupt signal, the indexer has drained by then
+	<-ctx.Done()
+	log.Println("Received signal, shutting down")
+	shutdownServers(shutdown)
+	return nil
+}
 
-	sig := <-sigChan
-	log.Println("Received signal:", sig)
+// signalContext returns a context that is done on an interrupt or termination signal
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
 
-	return nil
+// shutdownServers shuts the servers down, giving them the shutdown timeout to finish their requests
+func shutdownServers(shutdown func(ctx context.Context)) {
+	timeout := time.Duration(cfg.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	shutdown(ctx)
+	log.Println("Servers shut down")
 }
 
-func runServer(c *cli.Context) error {
+// newHealthChecker creates the health checks of the servers, the indexer adds its own per chain.
+// Readiness fails once ctx is done so that no new requests are routed while shutting down.
+func newHealthChecker(ctx context.Context) *ginhelper.HealthChecker {
+	health := ginhelper.NewHealthChecker()
+	health.AddReadinessCheck("database", ds.Ping)
+	health.AddReadinessCheck("shutdown", func(context.Context) error {
+		if ctx.Err() != nil {
+			return errors.New("shutting down")
+		}
+		return nil
+	})
+	return health
+}
+
+func runRetryFailed(c *cli.Context) error {
 	if err := config.InitConfig(c.String("config"), &cfg); err != nil {
 		return err
 	}
-	log.Println("Running the application with arguments:", c.Args().Slice())
+	log.Println("Retrying failed blocks with arguments:", c.Args().Slice())
+
+	ctx, stop := signalContext()
+	defer stop()
 
 	ds = data.Initialize(&cfg)
-	bds := data.NewBlockchainDataStore(ds)
+	bds := data.NewBlockchainDataStore(ds, cfg.Indexer.BatchSize)
+	var errs []error
+	for _, chain := range cfg.IndexedChains() {
+		errs = append(errs, indexer.RetryFailedBlocks(ctx, chain, bds, cfg.Indexer, cfg.Contracts))
+	}
+	return errors.Join(errs...)
+}
 
-	go StartServers(cfg.ServerConfig, bds)
-	// Wait for an interrupt signal to gracefully shut down the server
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+func runServer(c *cli.Context) error {
+	if err := config.InitConfig(c.String("config"), &cfg); err != nil {
+		return err
+	}
+	log.Println("Running the application with arguments:", c.Args().Slice())
+
+	ctx, stop := signalContext()
+	defer stop()
 
-	sig := <-sigChan
-	log.Println("Received signal:", sig)
+	ds = data.Initialize(&cfg)
+	bds := data.NewBlockchainDataStore(ds, cfg.Indexer.BatchSize)
 
+	shutdown := StartServers(cfg.ServerConfig, bds, newHealthChecker(ctx))
+	// Wait for an interrupt signal to gracefully shut down the servers
+	<-ctx.Done()
+	log.Println("Received signal, shutting down")
+	shutdownServers(shutdown)
 	return nil
 }
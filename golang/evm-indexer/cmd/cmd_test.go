@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShutdownServersGetsShutdownTimeout(t *testing.T) {
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+
+	for _, test := range []struct {
+		seconds int
+		want    time.Duration
+	}{
+		{0, defaultShutdownTimeout},
+		{3, 3 * time.Second},
+	} {
+		cfg.ShutdownTimeout = test.seconds
+		var remaining time.Duration
+		shutdownServers(func(ctx context.Context) {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatal("shutdown context without deadline")
+			}
+			remaining = time.Until(deadline)
+		})
+		if remaining > test.want || remaining < test.want-time.Second {
+			t.Errorf("shutdownTimeout %d gave the servers %s, want %s", test.seconds, remaining, test.want)
+		}
+	}
+}
@@ -1,43 +1,88 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	coreData "github.com/synkube/app/core/data"
 	"github.com/synkube/app/core/ginhelper"
 	"github.com/synkube/app/evm-indexer/data"
+	"github.com/synkube/app/evm-indexer/etherscan"
 	"github.com/synkube/app/evm-indexer/graphql/graph"
+	"github.com/synkube/app/evm-indexer/grpcapi"
+	"github.com/synkube/app/evm-indexer/rest"
 )
 
-func StartServers(servers []coreData.ServerConfig, bds *data.BlockchainDataStore) {
+// shutdownFunc stops a server from accepting requests and waits for the ongoing ones until ctx is done
+type shutdownFunc func(ctx context.Context) error
+
+// StartServers starts the servers in the background and returns a function shutting them all down
+func StartServers(servers []coreData.ServerConfig, bds *data.BlockchainDataStore, health *ginhelper.HealthChecker) func(ctx context.Context) {
+	var shutdowns []shutdownFunc
 	for _, server := range servers {
 		switch server.Type {
 		case "http":
-			go startHTTPServer(server)
+			shutdowns = append(shutdowns, startHTTPServer(server, bds, health))
 		case "grpc":
-			// Add gRPC server initialization here
+			shutdowns = append(shutdowns, startGRPCServer(server, bds))
 		case "graphql":
-			go startGraphQLServer(server, bds)
+			shutdowns = append(shutdowns, startGraphQLServer(server, bds))
 		case "websocket":
-			// Add WebSocket server initialization here
+			shutdowns = append(shutdowns, startWebsocketServer(server, bds))
 		default:
 			log.Printf("Unsupported server type: %s", server.Type)
 		}
 	}
 
-	// Keep the main routine running
-	select {}
+	return func(ctx context.Context) {
+		var wg sync.WaitGroup
+		for _, shutdown := range shutdowns {
+			wg.Add(1)
+			go func(shutdown shutdownFunc) {
+				defer wg.Done()
+				if err := shutdown(ctx); err != nil {
+					log.Printf("Failed to shut down server: %v", err)
+				}
+			}(shutdown)
+		}
+		wg.Wait()
+	}
+}
+
+// serveHTTP serves handler on addr in the background
+func serveHTTP(name, addr string, handler http.Handler) shutdownFunc {
+	srv := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		log.Printf("%s server is running on %s...\n", name, addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("%s server failed: %v", name, err)
+		}
+	}()
+	return func(ctx context.Context) error {
+		log.Printf("Shutting down %s server on %s", name, addr)
+		return srv.Shutdown(ctx)
+	}
 }
 
-func startHTTPServer(cfg coreData.ServerConfig) {
-	addr := fmt.Sprintf("localhost:%d", cfg.Port)
-	r := ginhelper.New([]string{ginhelper.HealthCheckRoute, ginhelper.RobotsTxtRoute})
+func startHTTPServer(cfg coreData.ServerConfig, bds *data.BlockchainDataStore, health *ginhelper.HealthChecker) shutdownFunc {
+	// Probes reach the server from outside the pod
+	addr := fmt.Sprintf(":%d", cfg.Port)
+	r := ginhelper.New([]string{ginhelper.RobotsTxtRoute})
+	health.Register(r)
 
 	r.GET("/", func(c *gin.Context) {
 		c.String(http.StatusOK, "Hello, World!")
@@ -45,16 +90,18 @@ func startHTTPServer(cfg coreData.ServerConfig) {
 
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
-	log.Printf("HTTP server is running on %s...\n", addr)
-	log.Fatal(r.Run(addr))
+	rest.RegisterRoutes(r, bds)
+	etherscan.RegisterRoutes(r, bds)
+
+	return serveHTTP("HTTP", addr, r)
 }
 
-func startGraphQLServer(cfg coreData.ServerConfig, bds *data.BlockchainDataStore) {
+func startGraphQLServer(cfg coreData.ServerConfig, bds *data.BlockchainDataStore) shutdownFunc {
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	r := ginhelper.New([]string{})
 
 	// GraphQL handler
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{BDS: bds}}))
+	srv := newGraphQLHandler(bds)
 
 	// GraphQL Playground handler
 	playgroundHandler := playground.Handler("GraphQL Playground", "/query")
@@ -63,8 +110,80 @@ func startGraphQLServer(cfg coreData.ServerConfig, bds *data.BlockchainDataStore
 	r.GET("/", gin.WrapH(playgroundHandler))
 	r.GET("/graphql", gin.WrapH(playgroundHandler))
 	r.GET("/gq", gin.WrapH(playgroundHandler))
+	r.GET("/query", gin.WrapH(srv)) // Websocket subscriptions
 	r.POST("/query", gin.WrapH(srv))
 
-	log.Printf("GraphQL server is running on %s...\n", addr)
-	log.Fatal(r.Run(addr))
+	return serveHTTP("GraphQL", addr, r)
+}
+
+func startGRPCServer(cfg coreData.ServerConfig, bds *data.BlockchainDataStore) shutdownFunc {
+	addr := fmt.Sprintf(":%d", cfg.Port)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", addr, err)
+	}
+
+	srv := grpcapi.NewGRPCServer(bds)
+	go func() {
+		log.Printf("gRPC server is running on %s...\n", addr)
+		if err := srv.Serve(lis); err != nil {
+			log.Fatalf("gRPC server failed: %v", err)
+		}
+	}()
+	return func(ctx context.Context) error {
+		log.Printf("Shutting down gRPC server on %s", addr)
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			// Streams like StreamBlocks never end on their own
+			srv.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// startWebsocketServer serves only the GraphQL subscriptions, on their own port
+func startWebsocketServer(cfg coreData.ServerConfig, bds *data.BlockchainDataStore) shutdownFunc {
+	addr := fmt.Sprintf(":%d", cfg.Port)
+	r := ginhelper.New([]string{})
+
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{BDS: bds}}))
+	srv.AddTransport(websocketTransport())
+	srv.Use(graph.LoaderExtension{BDS: bds})
+	r.GET("/query", gin.WrapH(srv))
+
+	return serveHTTP("Websocket", addr, r)
+}
+
+// newGraphQLHandler creates the GraphQL handler serving queries over HTTP and subscriptions over websockets
+func newGraphQLHandler(bds *data.BlockchainDataStore) *handler.Server {
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{BDS: bds}}))
+	srv.AddTransport(websocketTransport())
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New(1000))
+
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New(100)})
+	srv.Use(graph.LoaderExtension{BDS: bds})
+	return srv
+}
+
+// websocketTransport accepts subscriptions from any origin, like the CORS policy of the HTTP routes
+func websocketTransport() transport.Websocket {
+	return transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
 }
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"strconv"
+
 	"github.com/synkube/app/core/data"
 )
 
+// LatestBlock is the endBlock value that makes the indexer follow the chain head
+const LatestBlock = "latest"
+
 // Config represents the structure of the configuration file
 type Config struct {
 	AppName      string              `yaml:"appName"`
@@ -11,17 +16,83 @@ type Config struct {
 	ServerConfig []data.ServerConfig `yaml:"serverConfig"`
 	DbConfig     data.DbConfig       `yaml:"dbConfig"`
 	Indexer      Indexer             `yaml:"indexer"`
-	Chain        data.Chain          `yaml:"chain"`
+	Chains       []data.Chain        `yaml:"chains"`
+	Chain        data.Chain          `yaml:"chain"` // Single chain of older configuration files
+	Contracts    []Contract          `yaml:"contracts"`
+	// ShutdownTimeout is the number of seconds the servers get to finish their requests on
+	// shutdown.
+	ShutdownTimeout int `yaml:"shutdownTimeout"`
+}
+
+// IndexedChains returns the chains to index: Chains and, when configured, the single Chain
+func (c *Config) IndexedChains() []data.Chain {
+	chains := make([]data.Chain, 0, len(c.Chains)+1)
+	chains = append(chains, c.Chains...)
+	if len(c.Chain.RPCs) == 0 {
+		return chains
+	}
+	for _, chain := range c.Chains {
+		if chain.ID == c.Chain.ID {
+			return chains
+		}
+	}
+	return append(chains, c.Chain)
+}
+
+// Contract is a contract whose logs and calldata are decoded with its ABI
+type Contract struct {
+	Name    string `yaml:"name"`
+	Address string `yaml:"address"`
+	ABI     string `yaml:"abi"`     // Path to the ABI JSON file
+	ChainID int    `yaml:"chainId"` // Chain of the contract, 0 for every chain
 }
 
 type Indexer struct {
-	StartBlock    int `yaml:"startBlock"`
-	EndBlock      int `yaml:"endBlock"`
+	StartBlock int `yaml:"startBlock"`
+	// EndBlock is either a block number or "latest". When it is "latest" or
+	// omitted the indexer keeps following the chain head.
+	EndBlock string `yaml:"endBlock"`
+	// Confirmations is the number of blocks to stay behind the chain head
+	// when following it.
+	Confirmations int `yaml:"confirmations"`
+	// PollInterval is the number of seconds between chain head polls.
+	PollInterval int `yaml:"pollInterval"`
+	// MaxReorgDepth is the deepest chain reorganization that is rolled back: reorgs replacing up
+	// to MaxReorgDepth blocks, included, are handled.
+	MaxReorgDepth int `yaml:"maxReorgDepth"`
 	BatchSize     int `yaml:"batchSize"`
 	MaxWorkers    int `yaml:"maxWorkers"`
 	MaxRetries    int `yaml:"maxRetries"`
+	// MaxBatchItems is the most calls sent in one JSON-RPC batch request, larger batches are
+	// split. 0 uses 1000, the default limit of geth.
+	MaxBatchItems int `yaml:"maxBatchItems"`
+	// MaxBlockRetries is the number of times a failed block is re-queued before it is
+	// recorded as a failed block.
+	MaxBlockRetries int `yaml:"maxBlockRetries"`
+	// RetryInterval is the number of seconds before the first retry of a failed block.
 	RetryInterval int `yaml:"retryInterval"`
-	RetryBackoff  int `yaml:"retryBackoff"`
+	// RetryBackoff multiplies the delay between retries of a failed block.
+	RetryBackoff int `yaml:"retryBackoff"`
+	// TraceInternalTransactions indexes internal transactions with debug_traceBlockByNumber.
+	// Leave it off for nodes without the debug namespace.
+	TraceInternalTransactions bool `yaml:"traceInternalTransactions"`
+	// MaxLag is the number of blocks the indexer may fall behind the chain head before /readyz
+	// fails. 0 turns the check off.
+	MaxLag int `yaml:"maxLag"`
+	// DrainTimeout is the number of seconds the blocks in flight get to finish on shutdown
+	// before they are cancelled.
+	DrainTimeout int `yaml:"drainTimeout"`
+}
+
+// FollowHead reports whether the indexer should keep following the chain head
+// instead of stopping at a fixed end block
+func (i Indexer) FollowHead() bool {
+	return i.EndBlock == "" || i.EndBlock == LatestBlock
+}
+
+// EndBlockNumber parses the fixed end block, only valid when FollowHead is false
+func (i Indexer) EndBlockNumber() (int, error) {
+	return strconv.Atoi(i.EndBlock)
 }
 
 func InitConfig(cfgFile string, cfg *Config) error {
@@ -1,28 +1,118 @@
 package data
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/synkube/app/core/data"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// DefaultBatchSize is the number of rows per bulk insert when no batch size is configured
+const DefaultBatchSize = 100
+
+// clickhouseDialect is the GORM dialect name of ClickHouse, which lacks transactions and upserts
+const clickhouseDialect = "clickhouse"
+
+// mysqlDialect is the GORM dialect name of MySQL, whose upserts cannot be conditional
+const mysqlDialect = "mysql"
+
+// ErrChainRequired is returned for lookups by block number on a store spanning several chains, where
+// the number does not identify a block
+var ErrChainRequired = errors.New("a chain ID is required when several chains are indexed")
+
+// ErrInvalidAddress is returned for lookups by an address that is not a hex encoded address
+var ErrInvalidAddress = errors.New("invalid address")
+
 // BlockchainDataStore wraps the GORM DB instance to provide higher-level operations
 type BlockchainDataStore struct {
-	ds *data.DataStore
+	ds        *data.DataStore
+	batchSize int
+	chainID   uint64
+	scoped    bool            // Whether reads and deletes are limited to chainID
+	events    *EventBus       // Shared by the stores of every chain
+	ctx       context.Context // Cancels the queries of the store, nil for none
+}
+
+// NewBlockchainDataStore creates a new BlockchainDataStore inserting rows in batches of batchSize
+func NewBlockchainDataStore(ds *data.DataStore, batchSize int) *BlockchainDataStore {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &BlockchainDataStore{ds: ds, batchSize: batchSize, events: NewEventBus()}
+}
+
+// Events returns the bus saved blocks are published to
+func (bds *BlockchainDataStore) Events() *EventBus {
+	return bds.events
+}
+
+// ForChain returns a store limited to a single chain. Records saved through it are stamped with
+// the chain ID and queries only return records of that chain.
+func (bds *BlockchainDataStore) ForChain(chainID uint64) *BlockchainDataStore {
+	scoped := *bds
+	scoped.chainID = chainID
+	scoped.scoped = true
+	return &scoped
+}
+
+// WithContext returns a store whose queries are cancelled with ctx
+func (bds *BlockchainDataStore) WithContext(ctx context.Context) *BlockchainDataStore {
+	scoped := *bds
+	scoped.ctx = ctx
+	return &scoped
+}
+
+// conn returns the database bound to the context of the store, spanning every chain
+func (bds *BlockchainDataStore) conn() *gorm.DB {
+	if bds.ctx == nil {
+		return bds.ds.DB()
+	}
+	return bds.ds.DB().WithContext(bds.ctx)
 }
 
-// NewBlockchainDataStore creates a new BlockchainDataStore
-func NewBlockchainDataStore(ds *data.DataStore) *BlockchainDataStore {
-	return &BlockchainDataStore{ds: ds}
+// db returns the database limited to the chain of the store
+func (bds *BlockchainDataStore) db() *gorm.DB {
+	return bds.scope(bds.conn())
+}
+
+// scope limits a query to the chain of the store, stores without a chain see every chain
+func (bds *BlockchainDataStore) scope(db *gorm.DB) *gorm.DB {
+	if !bds.scoped {
+		return db
+	}
+	return db.Where("chain_id = ?", bds.chainID)
 }
 
-// SaveBlock saves a block and its transactions to the database
-func (bds *BlockchainDataStore) SaveBlock(block *Block, transactions []*Transaction, accounts []*Account) error {
+// IndexedBlock groups a block with the records indexed from it so they are saved together
+type IndexedBlock struct {
+	Block                *Block
+	Transactions         []*Transaction
+	Accounts             []*Account
+	Balances             []*AccountBalance
+	Receipts             []*Receipt
+	Logs                 []*Log
+	InternalTransactions []*InternalTransaction // Empty when tracing is turned off
+	Contracts            []*Contract
+	TokenTransfers       []*TokenTransfer
+	DecodedEvents        []*DecodedEvent
+}
+
+// SaveBlock saves a block and the records indexed from it to the database. Everything is written
+// in a single database transaction, so a block is either fully indexed or not at all.
+func (bds *BlockchainDataStore) SaveBlock(indexed *IndexedBlock) error {
+	block := indexed.Block
 	log.Printf("Starting to save block number %d", block.Number)
 
 	// Check if the block already exists
@@ -35,40 +125,213 @@ func (bds *BlockchainDataStore) SaveBlock(block *Block, transactions []*Transact
 		return nil
 	}
 
-	// Save transactions sequentially
-	txsIds := make([]string, 0, len(transactions))
-	for _, txn := range transactions {
-		if err := bds.SaveTransaction(txn); err != nil {
-			log.Printf("Error saving transaction %s: %v", txn.ID, err)
-			return err
-		}
-		txsIds = append(txsIds, txn.ID)
+	// Update block details
+	block.NumberOfTxs = uint64(len(indexed.Transactions))
+	indexed.setChainID(bds.chainID)
+
+	if bds.supportsTransactions() {
+		err = bds.conn().Transaction(func(tx *gorm.DB) error {
+			return bds.saveIndexedBlock(tx, indexed)
+		})
+	} else {
+		err = bds.saveBlockWithoutTransaction(indexed)
+	}
+	if err != nil {
+		log.Printf("Error saving block number %d: %v", block.Number, err)
+		return err
 	}
 
-	// Save accounts sequentially
-	for _, account := range accounts {
-		if err := bds.SaveAccount(account); err != nil {
-			log.Printf("Error saving account %s: %v", account.Address, err)
-			return err
+	log.Printf("Block number %d saved successfully", block.Number)
+	return nil
+}
+
+// setChainID stamps the block and its records with the chain they were indexed from
+func (indexed *IndexedBlock) setChainID(chainID uint64) {
+	indexed.Block.ChainID = chainID
+	for _, tx := range indexed.Transactions {
+		tx.ChainID = chainID
+	}
+	for _, account := range indexed.Accounts {
+		account.ChainID = chainID
+	}
+	for _, balance := range indexed.Balances {
+		balance.ChainID = chainID
+	}
+	for _, receipt := range indexed.Receipts {
+		receipt.ChainID = chainID
+	}
+	for _, l := range indexed.Logs {
+		l.ChainID = chainID
+	}
+	for _, internalTx := range indexed.InternalTransactions {
+		internalTx.ChainID = chainID
+	}
+	for _, contract := range indexed.Contracts {
+		contract.ChainID = chainID
+	}
+	for _, transfer := range indexed.TokenTransfers {
+		transfer.ChainID = chainID
+	}
+	for _, event := range indexed.DecodedEvents {
+		event.ChainID = chainID
+	}
+}
+
+// supportsTransactions reports whether the database supports transactions, ClickHouse does not
+func (bds *BlockchainDataStore) supportsTransactions() bool {
+	return bds.conn().Dialector.Name() != clickhouseDialect
+}
+
+// supportsUpsert reports whether the database supports ON CONFLICT clauses, ClickHouse does not
+func (bds *BlockchainDataStore) supportsUpsert() bool {
+	return bds.conn().Dialector.Name() != clickhouseDialect
+}
+
+// saveBlockWithoutTransaction is the fallback for databases without transactions. Leftovers of an
+// interrupted write are removed before saving and records written so far are removed if saving
+// fails. As the block row is written last, it is only present once everything else is.
+func (bds *BlockchainDataStore) saveBlockWithoutTransaction(indexed *IndexedBlock) error {
+	hashes := []string{indexed.Block.Hash}
+	if err := bds.deleteBlockRecords(bds.conn(), hashes); err != nil {
+		return fmt.Errorf("failed to clean up partially saved block: %v", err)
+	}
+
+	if err := bds.saveIndexedBlock(bds.conn(), indexed); err != nil {
+		if cleanupErr := bds.deleteBlockRecords(bds.conn(), hashes); cleanupErr != nil {
+			log.Printf("Error cleaning up partially saved block number %d: %v", indexed.Block.Number, cleanupErr)
 		}
+		return err
 	}
+	return nil
+}
 
-	// Update block details and save it
-	block.NumberOfTxs = uint64(len(transactions))
+// saveIndexedBlock writes a block and its records, the block row is saved last as it marks the block as indexed
+func (bds *BlockchainDataStore) saveIndexedBlock(db *gorm.DB, indexed *IndexedBlock) error {
+	if err := bds.insertRows(db, indexed.Transactions); err != nil {
+		log.Printf("Error saving transactions: %v", err)
+		return err
+	}
+	if err := bds.insertAccounts(db, indexed.Accounts); err != nil {
+		log.Printf("Error saving accounts: %v", err)
+		return err
+	}
+	if err := bds.insertRows(db, indexed.Balances); err != nil {
+		log.Printf("Error saving account balances: %v", err)
+		return err
+	}
+	if err := bds.insertRows(db, indexed.Receipts); err != nil {
+		log.Printf("Error saving receipts: %v", err)
+		return err
+	}
+	if err := bds.insertRows(db, indexed.Logs); err != nil {
+		log.Printf("Error saving logs: %v", err)
+		return err
+	}
+	if err := bds.insertRows(db, indexed.InternalTransactions); err != nil {
+		log.Printf("Error saving internal transactions: %v", err)
+		return err
+	}
+	if err := bds.insertRows(db, indexed.Contracts); err != nil {
+		log.Printf("Error saving contracts: %v", err)
+		return err
+	}
+	if err := bds.insertRows(db, indexed.TokenTransfers); err != nil {
+		log.Printf("Error saving token transfers: %v", err)
+		return err
+	}
+	if err := bds.insertRows(db, indexed.DecodedEvents); err != nil {
+		log.Printf("Error saving decoded events: %v", err)
+		return err
+	}
 
-	if err := bds.ds.DB().Save(block).Error; err != nil {
-		log.Printf("Error saving block number %d: %v", block.Number, err)
+	// The block is indexed now, so it is no longer a failed block
+	if err := bds.scope(db).Where("number = ?", indexed.Block.Number).Delete(&FailedBlock{}).Error; err != nil {
+		log.Printf("Error clearing failed block: %v", err)
 		return err
 	}
 
-	log.Printf("Block number %d saved successfully", block.Number)
+	return db.Save(indexed.Block).Error
+}
+
+// insertRows bulk inserts a slice of rows in batches, replacing rows that already exist where the
+// database supports upserts
+func (bds *BlockchainDataStore) insertRows(db *gorm.DB, rows interface{}) error {
+	if bds.supportsUpsert() {
+		db = db.Clauses(clause.OnConflict{UpdateAll: true})
+	}
+	return db.CreateInBatches(rows, bds.batchSize).Error
+}
+
+// insertAccounts bulk inserts accounts. Stored accounts get the new balance unless they already
+// have the balance of a later block.
+func (bds *BlockchainDataStore) insertAccounts(db *gorm.DB, accounts []*Account) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+	if bds.supportsUpsert() {
+		return db.Clauses(accountUpsert(db.Dialector.Name())).CreateInBatches(accounts, bds.batchSize).Error
+	}
+
+	// Without upserts, look up the stored accounts in a single query and skip them, their latest
+	// balance is found in the account balances
+	addresses := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		addresses = append(addresses, account.Address)
+	}
+	var existing []string
+	if err := bds.scope(db).Model(&Account{}).Where("address IN ?", addresses).Pluck("address", &existing).Error; err != nil {
+		return err
+	}
+	stored := make(map[string]struct{}, len(existing))
+	for _, address := range existing {
+		stored[address] = struct{}{}
+	}
+
+	missing := make([]*Account, 0, len(accounts))
+	for _, account := range accounts {
+		if _, ok := stored[account.Address]; !ok {
+			missing = append(missing, account)
+		}
+	}
+	return db.CreateInBatches(missing, bds.batchSize).Error
+}
+
+// accountUpsert returns the conflict clause updating stored accounts with a balance of the same or a
+// later block. MySQL has no conditional ON DUPLICATE KEY UPDATE, so the condition is part of the
+// assignments there, the balance being assigned before its block.
+func accountUpsert(dialect string) clause.OnConflict {
+	if dialect == mysqlDialect {
+		return clause.OnConflict{DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "balance"}, Value: clause.Expr{SQL: "IF(VALUES(balance_block) >= balance_block, VALUES(balance), balance)"}},
+			{Column: clause.Column{Name: "balance_block"}, Value: clause.Expr{SQL: "GREATEST(VALUES(balance_block), balance_block)"}},
+		}}
+	}
+	return clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain_id"}, {Name: "address"}},
+		DoUpdates: clause.AssignmentColumns([]string{"balance", "balance_block"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "accounts.balance_block <= excluded.balance_block"},
+		}},
+	}
+}
+
+// deleteBlockRecords deletes the records indexed from the given blocks. Accounts are not tied to a
+// block and are left in place.
+func (bds *BlockchainDataStore) deleteBlockRecords(db *gorm.DB, hashes []string) error {
+	for _, model := range []interface{}{&Transaction{}, &AccountBalance{}, &Receipt{}, &Log{}, &InternalTransaction{}, &Contract{}, &TokenTransfer{}, &DecodedEvent{}} {
+		if err := bds.scope(db).Where("block_hash IN ?", hashes).Delete(model).Error; err != nil {
+			log.Printf("Error deleting %T records: %v", model, err)
+			return err
+		}
+	}
 	return nil
 }
 
 // SaveTransaction saves a transaction to the database
 func (bds *BlockchainDataStore) SaveTransaction(tx *Transaction) error {
 	log.Printf("Starting to save transaction %s", tx.ID)
-	if err := bds.ds.DB().Save(tx).Error; err != nil {
+	tx.ChainID = bds.chainID
+	if err := bds.insertRows(bds.conn(), []*Transaction{tx}); err != nil {
 		log.Printf("Error saving transaction %s: %v", tx.ID, err)
 		return err
 	}
@@ -79,30 +342,23 @@ func (bds *BlockchainDataStore) SaveTransaction(tx *Transaction) error {
 // blockExists checks if a block with the given number exists in the database
 func (bds *BlockchainDataStore) blockExists(number uint64) (bool, error) {
 	var count int64
-	if err := bds.ds.DB().Model(&Block{}).Where("number = ?", number).Count(&count).Error; err != nil {
+	if err := bds.db().Model(&Block{}).Where("number = ?", number).Count(&count).Error; err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-// SaveAccount saves an account to the database
+// SaveAccount saves an account to the database, the balance of a stored account is updated unless
+// it is from a later block
 func (bds *BlockchainDataStore) SaveAccount(account *Account) error {
-	var count int64
-	err := bds.ds.DB().Model(&Account{}).Where("address = ?", account.Address).Count(&count).Error
-	if err != nil {
-		return err
-	}
-	if count > 0 {
-		// Account already exists, skip saving
-		return nil
-	}
-	return bds.ds.DB().Save(account).Error
+	account.ChainID = bds.chainID
+	return bds.insertAccounts(bds.conn(), []*Account{account})
 }
 
 // GetLatestSavedBlock retrieves the latest saved block number from the database
 func (bds *BlockchainDataStore) GetLatestSavedBlock() (uint64, error) {
 	var count int64
-	if err := bds.ds.DB().Model(&Block{}).Count(&count).Error; err != nil {
+	if err := bds.db().Model(&Block{}).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	if count == 0 {
@@ -110,17 +366,50 @@ func (bds *BlockchainDataStore) GetLatestSavedBlock() (uint64, error) {
 	}
 
 	var block Block
-	if err := bds.ds.DB().Order("number desc").First(&block).Error; err != nil {
+	if err := bds.db().Order("number desc").First(&block).Error; err != nil {
 		return 0, err
 	}
 
 	return block.Number, nil
 }
 
+// GetIndexerState retrieves the indexing progress of a chain, nil if the chain has none yet
+func (bds *BlockchainDataStore) GetIndexerState(chainID uint64) (*IndexerState, error) {
+	var states []*IndexerState
+	if err := bds.conn().Where("chain_id = ?", chainID).Limit(1).Find(&states).Error; err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return states[0], nil
+}
+
+// GetIndexerStates retrieves the indexer state of every chain, or of the chain of a scoped store.
+func (bds *BlockchainDataStore) GetIndexerStates() ([]*IndexerState, error) {
+	var states []*IndexerState
+	if err := bds.db().Order("chain_id").Find(&states).Error; err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// SaveIndexerState records the indexing progress of a chain
+func (bds *BlockchainDataStore) SaveIndexerState(state *IndexerState) error {
+	state.UpdatedAt = time.Now()
+	if bds.supportsUpsert() {
+		return bds.conn().Clauses(clause.OnConflict{UpdateAll: true}).Create(state).Error
+	}
+	if err := bds.conn().Where("chain_id = ?", state.ChainID).Delete(&IndexerState{}).Error; err != nil {
+		return err
+	}
+	return bds.conn().Create(state).Error
+}
+
 // GetAllBlockNumbers retrieves all block numbers from the database
 func (bds *BlockchainDataStore) GetAllBlockNumbers() ([]uint64, error) {
 	var blockNumbers []uint64
-	rows, err := bds.ds.DB().Model(&Block{}).Select("number").Rows()
+	rows, err := bds.db().Model(&Block{}).Select("number").Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +429,7 @@ func (bds *BlockchainDataStore) GetAllBlockNumbers() ([]uint64, error) {
 // GetBlockNumbersInRange retrieves all block numbers in the specified range from the database
 func (bds *BlockchainDataStore) GetBlockNumbersInRange(startBlock, endBlock uint64) ([]uint64, error) {
 	var blockNumbers []uint64
-	rows, err := bds.ds.DB().Model(&Block{}).Select("number").Where("number >= ? AND number <= ?", startBlock, endBlock).Rows()
+	rows, err := bds.db().Model(&Block{}).Select("number").Where("number >= ? AND number <= ?", startBlock, endBlock).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -158,12 +447,28 @@ func (bds *BlockchainDataStore) GetBlockNumbersInRange(startBlock, endBlock uint
 }
 
 // identifyMissingBlocks identifies any missing blocks between startBlock and latestSavedBlock
-func (bds *BlockchainDataStore) IdentifyMissingBlocks(startBlock, latestSavedBlock uint64) []int {
+func (bds *BlockchainDataStore) IdentifyMissingBlocks(startBlock, latestSavedBlock uint64) ([]int, error) {
 	log.Printf("Identifying missing blocks between %d and %d", startBlock, latestSavedBlock)
-	allBlockNumbers, err := bds.GetBlockNumbersInRange(startBlock, latestSavedBlock)
+	missedBlocks, err := bds.FindMissingBlocks(startBlock, latestSavedBlock)
 	if err != nil {
 		log.Printf("Error retrieving block numbers in range: %v", err)
-		return nil
+		return nil, err
+	}
+	log.Printf("Found %d missing blocks", len(missedBlocks))
+	if len(missedBlocks) > 20 {
+		log.Printf("Missing blocks: %v...", missedBlocks[:20])
+	} else {
+		log.Printf("Missing blocks: %v", missedBlocks)
+	}
+
+	return missedBlocks, nil
+}
+
+// FindMissingBlocks returns the numbers of the blocks between startBlock and endBlock that are not stored
+func (bds *BlockchainDataStore) FindMissingBlocks(startBlock, endBlock uint64) ([]int, error) {
+	allBlockNumbers, err := bds.GetBlockNumbersInRange(startBlock, endBlock)
+	if err != nil {
+		return nil, err
 	}
 
 	blockNumberSet := make(map[uint64]struct{}, len(allBlockNumbers))
@@ -172,77 +477,621 @@ func (bds *BlockchainDataStore) IdentifyMissingBlocks(startBlock, latestSavedBlo
 	}
 
 	missedBlocks := []int{}
-	for blockNumber := startBlock; blockNumber <= latestSavedBlock; blockNumber++ {
+	for blockNumber := startBlock; blockNumber <= endBlock; blockNumber++ {
 		if _, exists := blockNumberSet[blockNumber]; !exists {
 			missedBlocks = append(missedBlocks, int(blockNumber))
 		}
 	}
-	log.Printf("Found %d missing blocks", len(missedBlocks))
-	if len(missedBlocks) > 20 {
-		log.Printf("Missing blocks: %v...", missedBlocks[:20])
-	} else {
-		log.Printf("Missing blocks: %v", missedBlocks)
-	}
-
-	return missedBlocks
+	return missedBlocks, nil
 }
 
-// GetAllBlocks retrieves all blocks from the database.
-func (bds *BlockchainDataStore) GetAllBlocks() ([]*Block, error) {
+// GetBlocksInRange retrieves the stored blocks between startBlock and endBlock in block order. The
+// range may span at most MaxBlockSpan blocks.
+func (bds *BlockchainDataStore) GetBlocksInRange(startBlock, endBlock uint64) ([]*Block, error) {
+	if err := checkBlockSpan(startBlock, endBlock); err != nil {
+		return nil, err
+	}
 	var blocks []*Block
-	if err := bds.ds.DB().Find(&blocks).Error; err != nil {
+	err := bds.db().Where("number >= ? AND number <= ?", startBlock, endBlock).Order("number, chain_id").Find(&blocks).Error
+	if err != nil {
 		return nil, err
 	}
 	return blocks, nil
 }
 
+// GetMissingBlockRanges returns the blocks between startBlock and endBlock that are not stored, as
+// ranges of consecutive blocks. The range may span at most MaxBlockSpan blocks.
+func (bds *BlockchainDataStore) GetMissingBlockRanges(startBlock, endBlock uint64) ([]BlockRange, error) {
+	if err := checkBlockSpan(startBlock, endBlock); err != nil {
+		return nil, err
+	}
+	missedBlocks, err := bds.FindMissingBlocks(startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	return CompressBlockRanges(missedBlocks), nil
+}
+
+// GetBlocks retrieves a page of the blocks matching the filter, up to first blocks after the cursor.
+func (bds *BlockchainDataStore) GetBlocks(filter BlockFilter, order BlockOrder, first int, after string) (*Page[*Block], error) {
+	query := bds.db().Model(&Block{})
+	query = whereBlockRange(query, "number", filter.FromBlock, filter.ToBlock)
+	query = whereTimeRange(query, "timestamp", filter.FromTime, filter.ToTime)
+	if filter.Miner != "" {
+		query = query.Where("miner = ?", normalizeAddress(filter.Miner))
+	}
+
+	k := keyset[*Block]{
+		columns: []keyColumn{{"number", true}, {"chain_id", true}, {"id", false}},
+		values:  func(b *Block) []interface{} { return []interface{}{b.Number, b.ChainID, b.ID} },
+		desc:    order.Desc,
+	}
+	switch order.Field {
+	case BlockOrderNumber, "":
+	case BlockOrderGasUsed:
+		k.columns[0] = keyColumn{"gas_used", true}
+		k.values = func(b *Block) []interface{} { return []interface{}{b.GasUsed, b.ChainID, b.ID} }
+	default:
+		return nil, fmt.Errorf("unsupported block order %q", order.Field)
+	}
+	return paginate(query, k, first, after)
+}
+
 // GetBlockByID retrieves a block by its ID from the database.
 func (bds *BlockchainDataStore) GetBlockByID(id string) (*Block, error) {
 	var block Block
-	if err := bds.ds.DB().Where("id = ?", id).First(&block).Error; err != nil {
+	if err := bds.db().Where("id = ?", normalizeHash(id)).First(&block).Error; err != nil {
 		return nil, err
 	}
 	return &block, nil
 }
 
-// GetAllTransactions retrieves all transactions from the database.
-func (bds *BlockchainDataStore) GetAllTransactions() ([]*Transaction, error) {
-	var transactions []*Transaction
-	if err := bds.ds.DB().Find(&transactions).Error; err != nil {
+// GetBlocksByHashes retrieves the stored blocks with the given hashes
+func (bds *BlockchainDataStore) GetBlocksByHashes(hashes []string) ([]*Block, error) {
+	var blocks []*Block
+	if err := bds.db().Where("hash IN ?", hashes).Find(&blocks).Error; err != nil {
 		return nil, err
 	}
-	return transactions, nil
+	return blocks, nil
+}
+
+// GetBlockByNumber retrieves the stored block with the given number, or nil if there is none. Stores
+// spanning several indexed chains return ErrChainRequired.
+func (bds *BlockchainDataStore) GetBlockByNumber(number uint64) (*Block, error) {
+	if !bds.scoped {
+		var chains int64
+		if err := bds.conn().Model(&Block{}).Distinct("chain_id").Count(&chains).Error; err != nil {
+			return nil, err
+		}
+		if chains > 1 {
+			return nil, ErrChainRequired
+		}
+	}
+	var blocks []*Block
+	if err := bds.db().Where("number = ?", number).Limit(1).Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	return blocks[0], nil
+}
+
+// RollbackBlocks deletes orphaned blocks and the records indexed from them after a chain
+// reorganization. Accounts whose balance came from an orphaned block get back their latest
+// remaining balance.
+func (bds *BlockchainDataStore) RollbackBlocks(hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	log.Printf("Rolling back %d orphaned blocks", len(hashes))
+
+	// Blocks are deleted first so an interrupted rollback never leaves a block without its records
+	rollback := func(db *gorm.DB) error {
+		var affected []*AccountBalance
+		if err := bds.scope(db).Model(&AccountBalance{}).Distinct("chain_id", "address").Where("block_hash IN ?", hashes).Find(&affected).Error; err != nil {
+			log.Printf("Error retrieving accounts of orphaned blocks: %v", err)
+			return err
+		}
+		if err := bds.scope(db).Where("hash IN ?", hashes).Delete(&Block{}).Error; err != nil {
+			log.Printf("Error deleting orphaned blocks: %v", err)
+			return err
+		}
+		if err := bds.deleteBlockRecords(db, hashes); err != nil {
+			return err
+		}
+		return restoreAccountBalances(db, affected)
+	}
+	if bds.supportsTransactions() {
+		return bds.conn().Transaction(rollback)
+	}
+	return rollback(bds.conn())
+}
+
+// restoreAccountBalances sets the balance of accounts back to their latest stored balance, and
+// deletes the accounts left without one
+func restoreAccountBalances(db *gorm.DB, accounts []*AccountBalance) error {
+	for _, account := range accounts {
+		var latest []*AccountBalance
+		err := db.Where("chain_id = ? AND address = ?", account.ChainID, account.Address).
+			Order("block_number DESC").Limit(1).Find(&latest).Error
+		if err != nil {
+			log.Printf("Error retrieving balances of account %s: %v", account.Address, err)
+			return err
+		}
+		stored := db.Model(&Account{}).Where("chain_id = ? AND address = ?", account.ChainID, account.Address)
+		if len(latest) == 0 {
+			err = stored.Delete(&Account{}).Error
+		} else {
+			err = stored.Updates(map[string]interface{}{"balance": latest[0].Balance, "balance_block": latest[0].BlockNumber}).Error
+		}
+		if err != nil {
+			log.Printf("Error restoring balance of account %s: %v", account.Address, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveFailedBlock records a block that could not be indexed, replacing an earlier record of it.
+func (bds *BlockchainDataStore) SaveFailedBlock(failed *FailedBlock) error {
+	failed.ChainID = bds.chainID
+	if bds.supportsUpsert() {
+		return bds.conn().Clauses(clause.OnConflict{UpdateAll: true}).Create(failed).Error
+	}
+	if err := bds.db().Where("number = ?", failed.Number).Delete(&FailedBlock{}).Error; err != nil {
+		return err
+	}
+	return bds.conn().Create(failed).Error
+}
+
+// GetFailedBlocks retrieves all failed blocks from the database.
+func (bds *BlockchainDataStore) GetFailedBlocks() ([]*FailedBlock, error) {
+	var failed []*FailedBlock
+	if err := bds.db().Order("number").Find(&failed).Error; err != nil {
+		return nil, err
+	}
+	return failed, nil
+}
+
+// GetTransactions retrieves a page of the transactions matching the filter, up to first transactions
+// after the cursor.
+func (bds *BlockchainDataStore) GetTransactions(filter TransactionFilter, order TransactionOrder, first int, after string) (*Page[*Transaction], error) {
+	query, err := bds.transactionQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	k, err := transactionKeyset(order)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(query, k, first, after)
+}
+
+// GetFirstTransactionPages retrieves the first page of the transactions of each address matching the
+// filter, as GetTransactions with the address as filter.Address does, in one query and one count.
+// Pages are keyed by checksummed address, the database needs window functions.
+func (bds *BlockchainDataStore) GetFirstTransactionPages(addresses []string, filter TransactionFilter, order TransactionOrder, first int) (map[string]*Page[*Transaction], error) {
+	normalized := make([]string, len(addresses))
+	pages := make(map[string]*Page[*Transaction], len(addresses))
+	for i, address := range addresses {
+		normalized[i] = normalizeAddress(address)
+		pages[normalized[i]] = &Page[*Transaction]{Edges: make([]Edge[*Transaction], 0)}
+	}
+	filter.Address = ""
+	query, err := bds.transactionQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	k, err := transactionKeyset(order)
+	if err != nil {
+		return nil, err
+	}
+
+	// A transaction between two of the addresses is on the pages of both
+	sent := query.Session(&gorm.Session{}).Select("transactions.*, from_address AS matched_account").
+		Where("from_address IN ?", normalized)
+	received := query.Session(&gorm.Session{}).Select("transactions.*, to_address AS matched_account").
+		Where("to_address IN ? AND to_address <> from_address", normalized)
+	matched := bds.conn().Raw("? UNION ALL ?", sent, received)
+
+	var counts []struct {
+		MatchedAccount string
+		Total          int64
+	}
+	err = bds.conn().Table("(?) AS matched", matched).Select("matched_account, COUNT(*) AS total").
+		Group("matched_account").Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		if page, ok := pages[count.MatchedAccount]; ok {
+			page.TotalCount = count.Total
+		}
+	}
+
+	limit := pageSize(first)
+	ranked := bds.conn().Table("(?) AS matched", matched).
+		Select("matched.*, ROW_NUMBER() OVER (PARTITION BY matched_account ORDER BY " + k.order() + ") AS account_row")
+	var rows []struct {
+		Transaction    `gorm:"embedded"`
+		MatchedAccount string
+	}
+	err = bds.conn().Table("(?) AS ranked", ranked).Where("account_row <= ?", limit+1).
+		Order("matched_account, account_row").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string][]*Transaction, len(pages))
+	for i := range rows {
+		records[rows[i].MatchedAccount] = append(records[rows[i].MatchedAccount], &rows[i].Transaction)
+	}
+	for address, page := range pages {
+		page.addEdges(records[address], k, limit)
+	}
+	return pages, nil
+}
+
+// transactionQuery returns the query of the transactions matching a filter
+func (bds *BlockchainDataStore) transactionQuery(filter TransactionFilter) (*gorm.DB, error) {
+	query := bds.db().Model(&Transaction{})
+	query = whereBlockRange(query, "block_number", filter.FromBlock, filter.ToBlock)
+	query = whereTimeRange(query, "timestamp", filter.FromTime, filter.ToTime)
+	if filter.Address != "" {
+		address := normalizeAddress(filter.Address)
+		query = query.Where("from_address = ? OR to_address = ?", address, address)
+	}
+	if filter.FromAddress != "" {
+		query = query.Where("from_address = ?", normalizeAddress(filter.FromAddress))
+	}
+	if filter.ToAddress != "" {
+		query = query.Where("to_address = ?", normalizeAddress(filter.ToAddress))
+	}
+	return whereMinAmount(query, "value", filter.MinValue)
+}
+
+// transactionKeyset returns the keyset of a transaction order
+func transactionKeyset(order TransactionOrder) (keyset[*Transaction], error) {
+	var k keyset[*Transaction]
+	switch order.Field {
+	case TransactionOrderBlock, "":
+		k = keyset[*Transaction]{
+			columns: []keyColumn{{"block_number", true}, {"transaction_index", true}, {"chain_id", true}, {"id", false}},
+			values: func(tx *Transaction) []interface{} {
+				return []interface{}{tx.BlockNumber, tx.TransactionIndex, tx.ChainID, tx.ID}
+			},
+		}
+	case TransactionOrderGas:
+		k = keyset[*Transaction]{
+			columns: []keyColumn{{"gas", true}, {"chain_id", true}, {"id", false}},
+			values:  func(tx *Transaction) []interface{} { return []interface{}{tx.Gas, tx.ChainID, tx.ID} },
+		}
+	default:
+		return k, fmt.Errorf("unsupported transaction order %q", order.Field)
+	}
+	k.desc = order.Desc
+	return k, nil
 }
 
 // GetTransactionByID retrieves a transaction by its ID from the database.
 func (bds *BlockchainDataStore) GetTransactionByID(id string) (*Transaction, error) {
 	var transaction Transaction
-	if err := bds.ds.DB().Where("id = ?", id).First(&transaction).Error; err != nil {
+	if err := bds.db().Where("id = ?", normalizeHash(id)).First(&transaction).Error; err != nil {
 		return nil, err
 	}
 	return &transaction, nil
 }
 
-// GetAllAccounts retrieves all accounts from the database.
-func (bds *BlockchainDataStore) GetAllAccounts() ([]*Account, error) {
-	var accounts []*Account
-	if err := bds.ds.DB().Find(&accounts).Error; err != nil {
+// GetTransactionsByBlockHashes retrieves the transactions of the given blocks in block order
+func (bds *BlockchainDataStore) GetTransactionsByBlockHashes(hashes []string) ([]*Transaction, error) {
+	var transactions []*Transaction
+	err := bds.db().Where("block_hash IN ?", hashes).Order("block_number, transaction_index").Find(&transactions).Error
+	if err != nil {
 		return nil, err
 	}
-	return accounts, nil
+	return transactions, nil
+}
+
+// ListTransactions retrieves up to limit transactions matching the filter, skipping the first offset.
+// Unlike GetTransactions it pages by position, for APIs that number their pages.
+func (bds *BlockchainDataStore) ListTransactions(filter TransactionFilter, order TransactionOrder, offset, limit int) ([]*Transaction, error) {
+	query, err := bds.transactionQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	k, err := transactionKeyset(order)
+	if err != nil {
+		return nil, err
+	}
+	return list(query, k, offset, limit)
+}
+
+// GetReceiptsByTransactionHashes retrieves the receipts of the given transactions
+func (bds *BlockchainDataStore) GetReceiptsByTransactionHashes(hashes []string) ([]*Receipt, error) {
+	var receipts []*Receipt
+	if err := bds.db().Where("transaction_hash IN ?", hashes).Find(&receipts).Error; err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// GetReceiptsByBlockHash retrieves the receipts of a block in transaction order
+func (bds *BlockchainDataStore) GetReceiptsByBlockHash(hash string) ([]*Receipt, error) {
+	var receipts []*Receipt
+	if err := bds.db().Where("block_hash = ?", hash).Order("transaction_index").Find(&receipts).Error; err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// GetTransactionsByIDs retrieves the transactions with the given hashes
+func (bds *BlockchainDataStore) GetTransactionsByIDs(ids []string) ([]*Transaction, error) {
+	var transactions []*Transaction
+	if err := bds.db().Where("id IN ?", ids).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetBlockByTime retrieves the last block at or before a time, or with closest set the first block
+// at or after it. It returns nil if there is none.
+func (bds *BlockchainDataStore) GetBlockByTime(t time.Time, closest string) (*Block, error) {
+	query := bds.db().Where("timestamp <= ?", t).Order("number desc")
+	if closest == "after" {
+		query = bds.db().Where("timestamp >= ?", t).Order("number")
+	}
+	var blocks []*Block
+	if err := query.Limit(1).Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	return blocks[0], nil
 }
 
-// GetAccountByAddress retrieves an account by its address from the database.
+// ListTokenTransfers retrieves up to limit token transfers matching the filter in chain order,
+// skipping the first offset.
+func (bds *BlockchainDataStore) ListTokenTransfers(filter TokenTransferFilter, desc bool, offset, limit int) ([]*TokenTransfer, error) {
+	query := bds.db().Model(&TokenTransfer{})
+	query = whereBlockRange(query, "block_number", filter.FromBlock, filter.ToBlock)
+	if filter.Address != "" {
+		address := normalizeAddress(filter.Address)
+		query = query.Where("from_address = ? OR to_address = ?", address, address)
+	}
+	if filter.Token != "" {
+		query = query.Where("token_address = ?", normalizeAddress(filter.Token))
+	}
+	if filter.Standard != "" {
+		query = query.Where("standard = ?", filter.Standard)
+	}
+
+	k := keyset[*TokenTransfer]{
+		columns: []keyColumn{{"block_number", true}, {"log_index", true}, {"batch_index", true}, {"chain_id", true}},
+		desc:    desc,
+	}
+	return list(query, k, offset, limit)
+}
+
+// ListLogs retrieves up to limit logs matching the filter in chain order, skipping the first offset.
+func (bds *BlockchainDataStore) ListLogs(filter LogFilter, offset, limit int) ([]*Log, error) {
+	query := bds.db().Model(&Log{})
+	query = whereBlockRange(query, "block_number", filter.FromBlock, filter.ToBlock)
+	if filter.Address != "" {
+		query = query.Where("address = ?", normalizeAddress(filter.Address))
+	}
+	for i, topic := range filter.Topics {
+		if topic != "" {
+			query = query.Where(fmt.Sprintf("topic%d = ?", i), strings.ToLower(topic))
+		}
+	}
+
+	k := keyset[*Log]{columns: []keyColumn{{"block_number", true}, {"log_index", true}, {"chain_id", true}}}
+	return list(query, k, offset, limit)
+}
+
+// GetInternalTransactions retrieves the internal transactions of a transaction in call order.
+func (bds *BlockchainDataStore) GetInternalTransactions(txHash string) ([]*InternalTransaction, error) {
+	var internalTxs []*InternalTransaction
+	if err := bds.db().Where("transaction_hash = ?", normalizeHash(txHash)).Order("trace_index").Find(&internalTxs).Error; err != nil {
+		return nil, err
+	}
+	return internalTxs, nil
+}
+
+// GetInternalTransactionsByTransactionHashes retrieves the internal transactions of the given
+// transactions, each in call order
+func (bds *BlockchainDataStore) GetInternalTransactionsByTransactionHashes(hashes []string) ([]*InternalTransaction, error) {
+	normalized := make([]string, len(hashes))
+	for i, hash := range hashes {
+		normalized[i] = normalizeHash(hash)
+	}
+	var internalTxs []*InternalTransaction
+	err := bds.db().Where("transaction_hash IN ?", normalized).Order("transaction_hash, trace_index").Find(&internalTxs).Error
+	if err != nil {
+		return nil, err
+	}
+	return internalTxs, nil
+}
+
+// GetAccounts retrieves a page of the accounts matching the filter, up to first accounts after the cursor.
+func (bds *BlockchainDataStore) GetAccounts(filter AccountFilter, order AccountOrder, first int, after string) (*Page[*Account], error) {
+	query := bds.db().Model(&Account{})
+	query = whereBlockRange(query, "balance_block", filter.FromBlock, filter.ToBlock)
+	query, err := whereMinAmount(query, "balance", filter.MinBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	var k keyset[*Account]
+	switch order.Field {
+	case AccountOrderAddress, "":
+		k = keyset[*Account]{
+			columns: []keyColumn{{"address", false}, {"chain_id", true}},
+			values:  func(a *Account) []interface{} { return []interface{}{a.Address, a.ChainID} },
+		}
+	case AccountOrderBalanceBlock:
+		k = keyset[*Account]{
+			columns: []keyColumn{{"balance_block", true}, {"address", false}, {"chain_id", true}},
+			values:  func(a *Account) []interface{} { return []interface{}{a.BalanceBlock, a.Address, a.ChainID} },
+		}
+	default:
+		return nil, fmt.Errorf("unsupported account order %q", order.Field)
+	}
+	k.desc = order.Desc
+	return paginate(query, k, first, after)
+}
+
+// GetAccountByAddress retrieves an account by its address, in any case, from the database.
 func (bds *BlockchainDataStore) GetAccountByAddress(address string) (*Account, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidAddress, address)
+	}
 	var account Account
-	if err := bds.ds.DB().Where("address = ?", address).First(&account).Error; err != nil {
+	if err := bds.db().Where("address = ?", normalizeAddress(address)).First(&account).Error; err != nil {
 		return nil, err
 	}
 	return &account, nil
 }
 
+// GetAccountsByAddresses retrieves the accounts with the given addresses
+func (bds *BlockchainDataStore) GetAccountsByAddresses(addresses []string) ([]*Account, error) {
+	normalized := make([]string, len(addresses))
+	for i, address := range addresses {
+		normalized[i] = normalizeAddress(address)
+	}
+	var accounts []*Account
+	if err := bds.db().Where("address IN ?", normalized).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetAccountBalanceAt retrieves the balance of an account at a block: the balance recorded at the
+// latest block up to it involving the account. It returns nil if there is none.
+func (bds *BlockchainDataStore) GetAccountBalanceAt(address string, blockNumber uint64) (*AccountBalance, error) {
+	var balances []*AccountBalance
+	err := bds.db().Where("address = ? AND block_number <= ?", normalizeAddress(address), blockNumber).
+		Order("block_number desc").Limit(1).Find(&balances).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(balances) == 0 {
+		return nil, nil
+	}
+	return balances[0], nil
+}
+
+// GetContractByAddress retrieves a contract by its address, or nil if no deployment of it was indexed.
+func (bds *BlockchainDataStore) GetContractByAddress(address string) (*Contract, error) {
+	var contracts []*Contract
+	if err := bds.db().Where("address = ?", normalizeAddress(address)).Limit(1).Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+	if len(contracts) == 0 {
+		return nil, nil
+	}
+	return contracts[0], nil
+}
+
+// GetContractsByCreator retrieves the contracts deployed by an account in block order. It returns up
+// to first contracts after the cursor.
+func (bds *BlockchainDataStore) GetContractsByCreator(creator string, first int, after string) ([]*Contract, error) {
+	query := bds.db().Model(&Contract{}).Where("creator = ?", normalizeAddress(creator))
+	if after != "" {
+		position, err := decodeCursorFields(after, 2)
+		if err != nil {
+			return nil, err
+		}
+		blockNumber, err := strconv.ParseUint(position[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidCursor, after, err)
+		}
+		query = query.Where("block_number > ? OR (block_number = ? AND address > ?)", blockNumber, blockNumber, position[1])
+	}
+
+	var contracts []*Contract
+	if err := query.Order("block_number, address").Limit(pageSize(first)).Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+	return contracts, nil
+}
+
+// ContractCursor returns the pagination cursor pointing at a contract
+func ContractCursor(contract *Contract) string {
+	return encodeCursor(contract.BlockNumber, contract.Address)
+}
+
+// GetTokenTransfers retrieves token transfers in chain order, optionally filtered by an address on
+// either side of the transfer and by token contract. It returns up to first transfers after the cursor.
+func (bds *BlockchainDataStore) GetTokenTransfers(address, token string, first int, after string) ([]*TokenTransfer, error) {
+	query := bds.db().Model(&TokenTransfer{})
+	if address != "" {
+		address = normalizeAddress(address)
+		query = query.Where("from_address = ? OR to_address = ?", address, address)
+	}
+	if token != "" {
+		query = query.Where("token_address = ?", normalizeAddress(token))
+	}
+	if after != "" {
+		position, err := decodeCursor(after, 3)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("block_number > ? OR (block_number = ? AND log_index > ?) OR (block_number = ? AND log_index = ? AND batch_index > ?)",
+			position[0], position[0], position[1], position[0], position[1], position[2])
+	}
+
+	var transfers []*TokenTransfer
+	err := query.Order("block_number, log_index, batch_index").Limit(pageSize(first)).Find(&transfers).Error
+	if err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// TokenTransferCursor returns the pagination cursor pointing at a token transfer
+func TokenTransferCursor(transfer *TokenTransfer) string {
+	return encodeCursor(transfer.BlockNumber, transfer.LogIndex, transfer.BatchIndex)
+}
+
+// GetDecodedEvents retrieves decoded events of a contract in block order, optionally filtered by
+// event or method name. It returns up to first events after the cursor.
+func (bds *BlockchainDataStore) GetDecodedEvents(contract, name string, first int, after string) ([]*DecodedEvent, error) {
+	query := bds.db().Model(&DecodedEvent{}).Where("contract_address = ?", normalizeAddress(contract))
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	if after != "" {
+		position, err := decodeCursorFields(after, 2)
+		if err != nil {
+			return nil, err
+		}
+		blockNumber, err := strconv.ParseUint(position[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidCursor, after, err)
+		}
+		query = query.Where("block_number > ? OR (block_number = ? AND id > ?)", blockNumber, blockNumber, position[1])
+	}
+
+	var events []*DecodedEvent
+	if err := query.Order("block_number, id").Limit(pageSize(first)).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// DecodedEventCursor returns the pagination cursor pointing at a decoded event
+func DecodedEventCursor(event *DecodedEvent) string {
+	return encodeCursor(event.BlockNumber, event.ID)
+}
+
 // CreateBlockData creates a Block struct from the raw block data
 func CreateBlockData(block *types.Block) *Block {
+	baseFee := ""
+	if block.BaseFee() != nil {
+		baseFee = block.BaseFee().String()
+	}
 	return &Block{
 		ID:     block.Hash().Hex(),
 		Hash:   block.Hash().Hex(),
@@ -257,44 +1106,148 @@ func CreateBlockData(block *types.Block) *Block {
 		Size:            block.Size(),
 		GasUsed:         block.GasUsed(),
 		GasLimit:        block.GasLimit(),
+		BaseFee:         baseFee,
 		Nonce:           fmt.Sprintf("%d", block.Nonce()),
 		ExtraData:       fmt.Sprintf("%x", block.Extra()),
 	}
 }
 
-// CreateTransactionData creates a Transaction struct from the raw transaction data
-func CreateTransactionData(tx *types.Transaction, block *types.Block) (*Transaction, error) {
-	chainID := tx.ChainId()
-	signer := types.NewEIP155Signer(chainID)
-
+// CreateTransactionData creates a Transaction struct from the raw transaction data. The sender is
+// recovered with the signer of the chain, index is the position of the transaction in the block.
+func CreateTransactionData(tx *types.Transaction, block *types.Block, index int, signer types.Signer) (*Transaction, error) {
 	// Extract the sender address from the transaction
 	from, err := types.Sender(signer, tx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract sender: %v", err)
 	}
-	to := tx.To()
-	if to == nil {
-		to = &common.Address{}
+	// Contract deployments have no recipient
+	var to *string
+	if tx.To() != nil {
+		address := tx.To().Hex()
+		to = &address
 	}
 
-	return &Transaction{
-		ID:          tx.Hash().Hex(),
-		BlockHash:   block.Hash().Hex(),
-		FromAddress: from.Hex(),
-		ToAddress:   to.Hex(),
-		Value:       tx.Value().String(),
-		Gas:         tx.Gas(),
-		GasPrice:    tx.GasPrice().String(),
-		InputData:   fmt.Sprintf("%x", tx.Data()),
-		Nonce:       tx.Nonce(),
-		Timestamp:   time.Unix(int64(block.Time()), 0),
-	}, nil
-}
-
-// CreateAccountData creates an Account struct from the raw account data
-func CreateAccountData(address common.Address, balance *big.Int) *Account {
+	transaction := &Transaction{
+		ID:               tx.Hash().Hex(),
+		BlockHash:        block.Hash().Hex(),
+		BlockNumber:      block.NumberU64(),
+		FromAddress:      from.Hex(),
+		ToAddress:        to,
+		Value:            tx.Value().String(),
+		Gas:              tx.Gas(),
+		GasPrice:         tx.GasPrice().String(),
+		InputData:        fmt.Sprintf("%x", tx.Data()),
+		Nonce:            tx.Nonce(),
+		TransactionIndex: uint64(index),
+		Timestamp:        time.Unix(int64(block.Time()), 0),
+		Type:             tx.Type(),
+	}
+
+	switch tx.Type() {
+	case types.DynamicFeeTxType, types.BlobTxType:
+		transaction.MaxFeePerGas = tx.GasFeeCap().String()
+		transaction.MaxPriorityFeePerGas = tx.GasTipCap().String()
+	}
+	if tx.Type() != types.LegacyTxType {
+		accessList, err := json.Marshal(tx.AccessList())
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode access list: %v", err)
+		}
+		transaction.AccessList = string(accessList)
+	}
+	if hashes := tx.BlobHashes(); len(hashes) > 0 {
+		blobHashes, err := json.Marshal(hashes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode blob hashes: %v", err)
+		}
+		transaction.BlobHashes = string(blobHashes)
+	}
+	return transaction, nil
+}
+
+// CreateContractData creates a Contract struct for a contract deployed in a block, without a
+// bytecode hash when it has no code
+func CreateContractData(address, creator, txHash string, block *types.Block, code []byte) *Contract {
+	bytecodeHash := ""
+	if len(code) > 0 {
+		bytecodeHash = crypto.Keccak256Hash(code).Hex()
+	}
+	return &Contract{
+		Address:         address,
+		Creator:         creator,
+		TransactionHash: txHash,
+		BlockHash:       block.Hash().Hex(),
+		BlockNumber:     block.NumberU64(),
+		BytecodeHash:    bytecodeHash,
+		Timestamp:       time.Unix(int64(block.Time()), 0),
+	}
+}
+
+// CreateReceiptData creates a Receipt struct from the raw receipt data
+func CreateReceiptData(receipt *types.Receipt) *Receipt {
+	contractAddress := ""
+	if receipt.ContractAddress != (common.Address{}) {
+		contractAddress = receipt.ContractAddress.Hex()
+	}
+	effectiveGasPrice := ""
+	if receipt.EffectiveGasPrice != nil {
+		effectiveGasPrice = receipt.EffectiveGasPrice.String()
+	}
+
+	return &Receipt{
+		TransactionHash:   receipt.TxHash.Hex(),
+		BlockHash:         receipt.BlockHash.Hex(),
+		BlockNumber:       receipt.BlockNumber.Uint64(),
+		TransactionIndex:  uint64(receipt.TransactionIndex),
+		Type:              receipt.Type,
+		Status:            receipt.Status,
+		GasUsed:           receipt.GasUsed,
+		CumulativeGasUsed: receipt.CumulativeGasUsed,
+		EffectiveGasPrice: effectiveGasPrice,
+		ContractAddress:   contractAddress,
+	}
+}
+
+// CreateLogData creates a Log struct from the raw log data
+func CreateLogData(l *types.Log) *Log {
+	topics := make([]string, 4)
+	for i, topic := range l.Topics {
+		if i < len(topics) {
+			topics[i] = topic.Hex()
+		}
+	}
+
+	return &Log{
+		ID:               fmt.Sprintf("%s-%d", l.TxHash.Hex(), l.Index),
+		BlockHash:        l.BlockHash.Hex(),
+		BlockNumber:      l.BlockNumber,
+		TransactionHash:  l.TxHash.Hex(),
+		TransactionIndex: uint64(l.TxIndex),
+		LogIndex:         uint64(l.Index),
+		Address:          l.Address.Hex(),
+		Topic0:           topics[0],
+		Topic1:           topics[1],
+		Topic2:           topics[2],
+		Topic3:           topics[3],
+		Data:             fmt.Sprintf("%x", l.Data),
+	}
+}
+
+// CreateAccountData creates an Account struct from the raw account data and the block its balance is at
+func CreateAccountData(address common.Address, balance *big.Int, blockNumber uint64) *Account {
 	return &Account{
-		Address: address.Hex(),
-		Balance: balance.String(),
+		Address:      address.Hex(),
+		Balance:      balance.String(),
+		BalanceBlock: blockNumber,
+	}
+}
+
+// CreateAccountBalanceData creates an AccountBalance struct recording the balance of an account at a block
+func CreateAccountBalanceData(account *Account, block *types.Block) *AccountBalance {
+	return &AccountBalance{
+		Address:     account.Address,
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash().Hex(),
+		Balance:     account.Balance,
 	}
 }
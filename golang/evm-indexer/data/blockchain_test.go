@@ -0,0 +1,273 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	coreData "github.com/synkube/app/core/data"
+	"github.com/synkube/app/evm-indexer/config"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// newTestStore creates a store on a fresh in-memory SQLite database
+func newTestStore(tb testing.TB, batchSize int) *BlockchainDataStore {
+	tb.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(tb.Name())
+	ds := Initialize(&config.Config{DbConfig: coreData.DbConfig{
+		Type:   "sqlite",
+		SQLite: coreData.SQLiteConfig{File: fmt.Sprintf("file:%s?mode=memory&cache=shared", name)},
+	}})
+	sqlDB, err := ds.DB().DB()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { sqlDB.Close() })
+	return NewBlockchainDataStore(ds, batchSize)
+}
+
+// syntheticBlock builds a block with txsPerBlock transactions between distinct accounts
+func syntheticBlock(number uint64, txsPerBlock int) *IndexedBlock {
+	timestamp := time.Unix(1700000000+int64(number)*12, 0).UTC()
+	block := &Block{
+		ID:        fmt.Sprintf("0x%064x", number),
+		Hash:      fmt.Sprintf("0x%064x", number),
+		Number:    number,
+		Timestamp: timestamp,
+	}
+
+	transactions := make([]*Transaction, 0, txsPerBlock)
+	accounts := make([]*Account, 0, 2*txsPerBlock)
+	for i := 0; i < txsPerBlock; i++ {
+		from := fmt.Sprintf("0x%040x", 2*(number*uint64(txsPerBlock)+uint64(i)))
+		to := fmt.Sprintf("0x%040x", 2*(number*uint64(txsPerBlock)+uint64(i))+1)
+		transactions = append(transactions, &Transaction{
+			ChainID:          1,
+			ID:               fmt.Sprintf("0x%032x%032x", number, i),
+			BlockHash:        block.Hash,
+			BlockNumber:      number,
+			FromAddress:      from,
+			ToAddress:        &to,
+			Value:            "1000000000000000000",
+			Gas:              21000,
+			GasPrice:         "1000000000",
+			TransactionIndex: uint64(i),
+			Timestamp:        timestamp,
+		})
+		accounts = append(accounts,
+			&Account{ChainID: 1, Address: from, Balance: "1", BalanceBlock: number},
+			&Account{ChainID: 1, Address: to, Balance: "2", BalanceBlock: number})
+	}
+
+	return &IndexedBlock{Block: block, Transactions: transactions, Accounts: accounts}
+}
+
+// storedTransactions returns the stored transactions in ID order, without their timestamps which
+// do not survive the round trip with the same location
+func storedTransactions(t *testing.T, bds *BlockchainDataStore) []Transaction {
+	t.Helper()
+	var rows []Transaction
+	if err := bds.db().Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := range rows {
+		rows[i].Timestamp = time.Time{}
+	}
+	return rows
+}
+
+func TestInsertRowsBatchedMatchesRowByRow(t *testing.T) {
+	block := syntheticBlock(1, 25)
+
+	var stored [][]Transaction
+	for _, batchSize := range []int{1, 7, DefaultBatchSize} {
+		bds := newTestStore(t, batchSize).ForChain(1)
+		if err := bds.insertRows(bds.conn(), block.Transactions); err != nil {
+			t.Fatalf("batch size %d: %v", batchSize, err)
+		}
+		stored = append(stored, storedTransactions(t, bds))
+	}
+
+	if len(stored[0]) != len(block.Transactions) {
+		t.Fatalf("stored %d transactions, want %d", len(stored[0]), len(block.Transactions))
+	}
+	for i := 1; i < len(stored); i++ {
+		if !reflect.DeepEqual(stored[0], stored[i]) {
+			t.Errorf("batched inserts stored different rows than row by row inserts")
+		}
+	}
+}
+
+func TestInsertRowsReplacesExistingRows(t *testing.T) {
+	bds := newTestStore(t, 7).ForChain(1)
+	block := syntheticBlock(1, 10)
+	if err := bds.insertRows(bds.conn(), block.Transactions); err != nil {
+		t.Fatal(err)
+	}
+
+	block.Transactions[3].Value = "42"
+	if err := bds.insertRows(bds.conn(), block.Transactions); err != nil {
+		t.Fatalf("inserting stored rows again: %v", err)
+	}
+
+	rows := storedTransactions(t, bds)
+	if len(rows) != 10 {
+		t.Fatalf("stored %d transactions, want 10", len(rows))
+	}
+	if rows[3].Value != "42" {
+		t.Errorf("value = %s, want the replaced value 42", rows[3].Value)
+	}
+}
+
+func TestInsertAccountsKeepsLatestBalance(t *testing.T) {
+	bds := newTestStore(t, 2).ForChain(1)
+	address := "0x0000000000000000000000000000000000000001"
+	insert := func(balance string, block uint64) {
+		t.Helper()
+		// Other accounts make the batch span several inserts
+		accounts := []*Account{
+			{ChainID: 1, Address: "0x0000000000000000000000000000000000000002", Balance: balance, BalanceBlock: block},
+			{ChainID: 1, Address: "0x0000000000000000000000000000000000000003", Balance: balance, BalanceBlock: block},
+			{ChainID: 1, Address: address, Balance: balance, BalanceBlock: block},
+		}
+		if err := bds.insertAccounts(bds.conn(), accounts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	insert("500", 5)
+	insert("300", 3) // Indexed out of order, older than the stored balance
+	account, err := bds.GetAccountByAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != "500" || account.BalanceBlock != 5 {
+		t.Errorf("balance %s at block %d, want 500 at block 5", account.Balance, account.BalanceBlock)
+	}
+
+	insert("700", 7)
+	account, err = bds.GetAccountByAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != "700" || account.BalanceBlock != 7 {
+		t.Errorf("balance %s at block %d, want 700 at block 7", account.Balance, account.BalanceBlock)
+	}
+
+	var count int64
+	if err := bds.db().Model(&Account{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("stored %d accounts, want 3", count)
+	}
+}
+
+// BenchmarkSaveBlock compares row by row inserts with batched inserts of blocks of 200 transactions
+func BenchmarkSaveBlock(b *testing.B) {
+	for _, bench := range []struct {
+		name      string
+		batchSize int
+	}{
+		{"RowByRow", 1},
+		{"Batched", DefaultBatchSize},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			bds := newTestStore(b, bench.batchSize).ForChain(1)
+			blocks := make([]*IndexedBlock, b.N)
+			for i := range blocks {
+				blocks[i] = syntheticBlock(uint64(i), 200)
+			}
+
+			b.ResetTimer()
+			for _, block := range blocks {
+				if err := bds.SaveBlock(block); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestContractDeploymentsHaveNullRecipient(t *testing.T) {
+	bds := newTestStore(t, 0).ForChain(1)
+	block := syntheticBlock(1, 2)
+	block.Transactions[0].ToAddress = nil
+	if err := bds.SaveBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	var deployments []*Transaction
+	if err := bds.db().Where("to_address IS NULL").Find(&deployments).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments) != 1 || deployments[0].ID != block.Transactions[0].ID {
+		t.Errorf("transactions without recipient = %v, want the deployment only", deployments)
+	}
+}
+
+func TestAccountUpsertOnMySQL(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user@tcp(localhost:3306)/db", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := []*Account{{ChainID: 1, Address: "0x0000000000000000000000000000000000000001", Balance: "1", BalanceBlock: 1}}
+	result := db.Clauses(accountUpsert(db.Dialector.Name())).Create(&accounts)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	stmt := result.Statement
+
+	want := "ON DUPLICATE KEY UPDATE `balance`=IF(VALUES(balance_block) >= balance_block, VALUES(balance), balance)," +
+		"`balance_block`=GREATEST(VALUES(balance_block), balance_block)"
+	if !strings.HasSuffix(stmt.SQL.String(), want) {
+		t.Errorf("upsert = %s, want the balance of older blocks kept", stmt.SQL.String())
+	}
+}
+
+func TestGetBlockByNumberRequiresChain(t *testing.T) {
+	bds := newTestStore(t, 0)
+	if err := bds.ForChain(1).SaveBlock(syntheticBlock(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if block, err := bds.GetBlockByNumber(1); err != nil || block == nil || block.ChainID != 1 {
+		t.Fatalf("block 1 of the only chain = %v, %v", block, err)
+	}
+
+	if err := bds.ForChain(2).SaveBlock(syntheticBlock(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bds.GetBlockByNumber(1); !errors.Is(err, ErrChainRequired) {
+		t.Errorf("block 1 of several chains error = %v, want ErrChainRequired", err)
+	}
+	if block, err := bds.ForChain(2).GetBlockByNumber(1); err != nil || block == nil || block.ChainID != 2 {
+		t.Errorf("block 1 of chain 2 = %v, %v", block, err)
+	}
+}
+
+func TestLookupsNormalizeHashes(t *testing.T) {
+	bds := newTestStore(t, 0).ForChain(1)
+	block := syntheticBlock(10, 1)
+	id := block.Transactions[0].ID
+	block.InternalTransactions = []*InternalTransaction{{ID: id + "-0", BlockHash: block.Block.Hash, BlockNumber: 10, TransactionHash: id, Type: "CALL", Value: "0", Depth: 1}}
+	if err := bds.SaveBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hash := range []string{strings.ToUpper(block.Block.Hash), "0X" + block.Block.Hash[2:], block.Block.Hash[2:]} {
+		if _, err := bds.GetBlockByID(hash); err != nil {
+			t.Errorf("block %s: %v", hash, err)
+		}
+	}
+	if _, err := bds.GetTransactionByID("0x" + strings.ToUpper(id[2:])); err != nil {
+		t.Errorf("transaction with uppercase hex: %v", err)
+	}
+	if internalTxs, err := bds.GetInternalTransactions(strings.ToUpper(id)); err != nil || len(internalTxs) != 1 {
+		t.Errorf("internal transactions of uppercase hash = %d, %v, want 1", len(internalTxs), err)
+	}
+}
@@ -10,8 +10,9 @@ import (
 
 // Block represents a block in the blockchain
 type Block struct {
+	ChainID   uint64    `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
 	ID        string    `json:"id" gorm:"primaryKey"`
-	Hash      string    `json:"hash" gorm:"uniqueIndex"`
+	Hash      string    `json:"hash" gorm:"index"`
 	Number    uint64    `json:"number"`
 	Timestamp time.Time `json:"timestamp"`
 	// Transactions    []Transaction `json:"transactions" gorm:"foreignKey:BlockHash;references:Hash"`
@@ -24,36 +25,198 @@ type Block struct {
 	Size            uint64 `json:"size"`
 	GasUsed         uint64 `json:"gasUsed"`
 	GasLimit        uint64 `json:"gasLimit"`
+	BaseFee         string `json:"baseFee"` // Empty before London
 	Nonce           string `json:"nonce"`
 	ExtraData       string `json:"extraData"`
 }
 
 // Transaction represents a transaction in the blockchain
 type Transaction struct {
-	ID               string    `json:"id" gorm:"primaryKey"`
-	BlockHash        string    `json:"blockHash" gorm:"index"`
-	FromAddress      string    `json:"fromAddress"`
-	ToAddress        string    `json:"toAddress"`
-	Value            string    `json:"value"`
-	Gas              uint64    `json:"gas"`
-	GasPrice         string    `json:"gasPrice"`
-	InputData        string    `json:"inputData"`
-	Nonce            uint64    `json:"nonce"`
-	TransactionIndex uint64    `json:"transactionIndex"`
-	Timestamp        time.Time `json:"timestamp"`
+	ChainID              uint64    `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	ID                   string    `json:"id" gorm:"primaryKey"`
+	BlockHash            string    `json:"blockHash" gorm:"index"`
+	BlockNumber          uint64    `json:"blockNumber" gorm:"index"`
+	FromAddress          string    `json:"fromAddress"`
+	ToAddress            *string   `json:"toAddress"` // Nil for contract deployments
+	Value                string    `json:"value"`
+	Gas                  uint64    `json:"gas"`
+	GasPrice             string    `json:"gasPrice"`
+	InputData            string    `json:"inputData"`
+	Nonce                uint64    `json:"nonce"`
+	TransactionIndex     uint64    `json:"transactionIndex"`
+	Timestamp            time.Time `json:"timestamp"`
+	Type                 uint8     `json:"type"`
+	MaxFeePerGas         string    `json:"maxFeePerGas"`         // Empty for legacy and access list transactions
+	MaxPriorityFeePerGas string    `json:"maxPriorityFeePerGas"` // Empty for legacy and access list transactions
+	AccessList           string    `json:"accessList"`           // JSON encoded, empty for legacy transactions
+	BlobHashes           string    `json:"blobHashes"`           // JSON encoded, empty for non blob transactions
+	CreatedContract      string    `json:"createdContract"`      // Address of the deployed contract
+}
+
+// Receipt represents the receipt of a transaction
+type Receipt struct {
+	ChainID           uint64 `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	TransactionHash   string `json:"transactionHash" gorm:"primaryKey"`
+	BlockHash         string `json:"blockHash" gorm:"index"`
+	BlockNumber       uint64 `json:"blockNumber"`
+	TransactionIndex  uint64 `json:"transactionIndex"`
+	Type              uint8  `json:"type"`
+	Status            uint64 `json:"status"`
+	GasUsed           uint64 `json:"gasUsed"`
+	CumulativeGasUsed uint64 `json:"cumulativeGasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	ContractAddress   string `json:"contractAddress"`
+}
+
+// Log represents an event log emitted by a transaction
+type Log struct {
+	ChainID          uint64 `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	ID               string `json:"id" gorm:"primaryKey"` // Transaction hash and log index
+	BlockHash        string `json:"blockHash" gorm:"index"`
+	BlockNumber      uint64 `json:"blockNumber" gorm:"index"`
+	TransactionHash  string `json:"transactionHash" gorm:"index"`
+	TransactionIndex uint64 `json:"transactionIndex"`
+	LogIndex         uint64 `json:"logIndex"`
+	Address          string `json:"address" gorm:"index"`
+	Topic0           string `json:"topic0" gorm:"index"`
+	Topic1           string `json:"topic1"`
+	Topic2           string `json:"topic2"`
+	Topic3           string `json:"topic3"`
+	Data             string `json:"data"`
+}
+
+// Contract represents a contract deployed by a transaction or by another contract
+type Contract struct {
+	ChainID         uint64    `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	Address         string    `json:"address" gorm:"primaryKey"`
+	Creator         string    `json:"creator" gorm:"index"`
+	TransactionHash string    `json:"transactionHash" gorm:"index"`
+	BlockHash       string    `json:"blockHash" gorm:"index"`
+	BlockNumber     uint64    `json:"blockNumber" gorm:"index"`
+	BytecodeHash    string    `json:"bytecodeHash"` // Keccak-256 hash of the deployed bytecode, empty without code
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// InternalTransaction represents a call made by a contract during a transaction, taken from the
+// call tracer of debug_traceBlockByNumber
+type InternalTransaction struct {
+	ChainID         uint64    `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	ID              string    `json:"id" gorm:"primaryKey"` // Transaction hash and trace index
+	BlockHash       string    `json:"blockHash" gorm:"index"`
+	BlockNumber     uint64    `json:"blockNumber" gorm:"index"`
+	TransactionHash string    `json:"transactionHash" gorm:"index"`
+	TraceIndex      uint64    `json:"traceIndex"` // Position in the call tree in depth-first order
+	Type            string    `json:"type"`       // CALL, DELEGATECALL, STATICCALL, CREATE, SELFDESTRUCT, ...
+	FromAddress     string    `json:"fromAddress" gorm:"index"`
+	ToAddress       *string   `json:"toAddress" gorm:"index"` // Nil for contract creations that failed
+	Value           string    `json:"value"`
+	Gas             uint64    `json:"gas"`
+	GasUsed         uint64    `json:"gasUsed"`
+	Depth           uint64    `json:"depth"` // 1 for calls made by the transaction itself
+	Error           string    `json:"error"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// Token standards of a TokenTransfer
+const (
+	StandardERC20   = "ERC20"
+	StandardERC721  = "ERC721"
+	StandardERC1155 = "ERC1155"
+)
+
+// TokenTransfer represents an ERC-20, ERC-721 or ERC-1155 token movement decoded from a log
+type TokenTransfer struct {
+	ChainID         uint64    `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	ID              string    `json:"id" gorm:"primaryKey"` // Log ID and position within a batch transfer
+	BlockHash       string    `json:"blockHash" gorm:"index"`
+	BlockNumber     uint64    `json:"blockNumber" gorm:"index"`
+	TransactionHash string    `json:"transactionHash" gorm:"index"`
+	LogIndex        uint64    `json:"logIndex"`
+	BatchIndex      uint64    `json:"batchIndex"`
+	TokenAddress    string    `json:"tokenAddress" gorm:"index"`
+	Standard        string    `json:"standard"` // ERC20, ERC721 or ERC1155
+	Operator        string    `json:"operator"`
+	FromAddress     string    `json:"fromAddress" gorm:"index"`
+	ToAddress       string    `json:"toAddress" gorm:"index"`
+	Amount          string    `json:"amount"`
+	TokenID         string    `json:"tokenId"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// Kinds of a DecodedEvent
+const (
+	DecodedKindEvent  = "event"
+	DecodedKindMethod = "method"
+)
+
+// DecodedEvent represents a log or calldata of a registered contract decoded with its ABI
+type DecodedEvent struct {
+	ChainID         uint64    `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	ID              string    `json:"id" gorm:"primaryKey"` // Log ID, or transaction hash for calldata
+	BlockHash       string    `json:"blockHash" gorm:"index"`
+	BlockNumber     uint64    `json:"blockNumber" gorm:"index"`
+	TransactionHash string    `json:"transactionHash" gorm:"index"`
+	LogIndex        uint64    `json:"logIndex"`
+	ContractAddress string    `json:"contractAddress" gorm:"index"`
+	ContractName    string    `json:"contractName"`
+	Kind            string    `json:"kind"` // event or method
+	Name            string    `json:"name" gorm:"index"`
+	Signature       string    `json:"signature"`
+	Args            string    `json:"args"` // JSON encoded arguments
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// FailedBlock represents a block that could not be indexed within its retry budget
+type FailedBlock struct {
+	ChainID  uint64    `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	Number   uint64    `json:"number" gorm:"primaryKey;autoIncrement:false"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// IndexerState records the indexing progress of a chain. Every block from the start block
+// below LowWaterMark is indexed or recorded as a FailedBlock, blocks between LowWaterMark and
+// HighWaterMark may have gaps.
+type IndexerState struct {
+	ChainID       uint64    `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	LowWaterMark  uint64    `json:"lowWaterMark"`
+	HighWaterMark uint64    `json:"highWaterMark"`
+	UpdatedAt     time.Time `json:"updatedAt"`
 }
 
 // Account represents an account in the blockchain
 type Account struct {
+	ChainID uint64 `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
 	Address string `json:"address" gorm:"primaryKey"`
-	Balance string `json:"balance"`
+	Balance string `json:"balance"` // Balance at BalanceBlock, the latest indexed block involving the account
+	// BalanceBlock is the block the balance was retrieved at
+	BalanceBlock uint64 `json:"balanceBlock"`
 	// Transactions []Transaction `json:"transactions" gorm:"foreignKey:FromAddress;references:Address"`
 }
 
+// AccountBalance represents the balance of an account at a block involving it
+type AccountBalance struct {
+	ChainID     uint64 `json:"chainId" gorm:"primaryKey;autoIncrement:false"`
+	Address     string `json:"address" gorm:"primaryKey"`
+	BlockNumber uint64 `json:"blockNumber" gorm:"primaryKey;autoIncrement:false"`
+	BlockHash   string `json:"blockHash" gorm:"index"`
+	Balance     string `json:"balance"`
+}
+
 var models = []interface{}{
 	&Account{},
+	&AccountBalance{},
 	&Block{},
 	&Transaction{},
+	&Receipt{},
+	&Log{},
+	&InternalTransaction{},
+	&Contract{},
+	&TokenTransfer{},
+	&DecodedEvent{},
+	&FailedBlock{},
+	&IndexerState{},
 }
 
 func Initialize(cfg *config.Config) *coreData.DataStore {
@@ -61,6 +224,9 @@ func Initialize(cfg *config.Config) *coreData.DataStore {
 	if cfg.DbConfig.Clean {
 		ds.Clean(models...)
 	}
+	if err := migrateChainIDs(ds.DB(), cfg); err != nil {
+		log.Fatalf("Failed to migrate the database: %v", err)
+	}
 	ds.Migrate(models...)
 	// Populate(ds)
 
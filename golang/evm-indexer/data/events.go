@@ -0,0 +1,56 @@
+package data
+
+import (
+	"context"
+	"sync"
+)
+
+// eventBufferSize is the number of events a subscriber can fall behind before missing events
+const eventBufferSize = 64
+
+// BlockEvent is published once a block and its transactions are saved
+type BlockEvent struct {
+	Block        *Block
+	Transactions []*Transaction
+}
+
+// EventBus delivers saved blocks to in-process subscribers such as GraphQL subscriptions
+type EventBus struct {
+	mutex       sync.RWMutex
+	subscribers map[chan *BlockEvent]struct{}
+}
+
+// NewEventBus creates an EventBus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[chan *BlockEvent]struct{})}
+}
+
+// Subscribe returns a channel receiving the events published until ctx is done, when it is closed.
+// Events are dropped for subscribers that fall behind so they never hold up the indexer.
+func (bus *EventBus) Subscribe(ctx context.Context) <-chan *BlockEvent {
+	events := make(chan *BlockEvent, eventBufferSize)
+	bus.mutex.Lock()
+	bus.subscribers[events] = struct{}{}
+	bus.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		bus.mutex.Lock()
+		delete(bus.subscribers, events)
+		close(events)
+		bus.mutex.Unlock()
+	}()
+	return events
+}
+
+// Publish delivers an event to every subscriber
+func (bus *EventBus) Publish(event *BlockEvent) {
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+	for events := range bus.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
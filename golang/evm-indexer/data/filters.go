@@ -0,0 +1,176 @@
+package data
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BlockOrderField is a field blocks can be ordered by
+type BlockOrderField string
+
+const (
+	BlockOrderNumber  BlockOrderField = "NUMBER"
+	BlockOrderGasUsed BlockOrderField = "GAS_USED"
+)
+
+// BlockOrder is the order of a page of blocks
+type BlockOrder struct {
+	Field BlockOrderField
+	Desc  bool
+}
+
+// BlockFilter restricts the blocks of a page, zero values match every block
+type BlockFilter struct {
+	FromBlock *uint64
+	ToBlock   *uint64
+	FromTime  *time.Time
+	ToTime    *time.Time
+	Miner     string
+}
+
+// TransactionOrderField is a field transactions can be ordered by
+type TransactionOrderField string
+
+const (
+	TransactionOrderBlock TransactionOrderField = "BLOCK"
+	TransactionOrderGas   TransactionOrderField = "GAS"
+)
+
+// TransactionOrder is the order of a page of transactions
+type TransactionOrder struct {
+	Field TransactionOrderField
+	Desc  bool
+}
+
+// TransactionFilter restricts the transactions of a page, zero values match every transaction
+type TransactionFilter struct {
+	FromBlock   *uint64
+	ToBlock     *uint64
+	FromTime    *time.Time
+	ToTime      *time.Time
+	Address     string // Either side of the transaction
+	FromAddress string
+	ToAddress   string
+	MinValue    string // Decimal amount in wei
+}
+
+// AccountOrderField is a field accounts can be ordered by
+type AccountOrderField string
+
+const (
+	AccountOrderAddress      AccountOrderField = "ADDRESS"
+	AccountOrderBalanceBlock AccountOrderField = "BALANCE_BLOCK"
+)
+
+// AccountOrder is the order of a page of accounts
+type AccountOrder struct {
+	Field AccountOrderField
+	Desc  bool
+}
+
+// AccountFilter restricts the accounts of a page, zero values match every account. The block range
+// applies to the block the balance was retrieved at.
+type AccountFilter struct {
+	FromBlock  *uint64
+	ToBlock    *uint64
+	MinBalance string // Decimal amount in wei
+}
+
+// TokenTransferFilter restricts listed token transfers, zero values match every transfer
+type TokenTransferFilter struct {
+	FromBlock *uint64
+	ToBlock   *uint64
+	Address   string // Either side of the transfer
+	Token     string
+	Standard  string // ERC20, ERC721 or ERC1155
+}
+
+// LogFilter restricts listed logs, zero values match every log
+type LogFilter struct {
+	FromBlock *uint64
+	ToBlock   *uint64
+	Address   string
+	Topics    [4]string
+}
+
+// whereBlockRange restricts a query to a range of block numbers in column
+func whereBlockRange(query *gorm.DB, column string, from, to *uint64) *gorm.DB {
+	if from != nil {
+		query = query.Where(column+" >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where(column+" <= ?", *to)
+	}
+	return query
+}
+
+// whereTimeRange restricts a query to a range of times in column
+func whereTimeRange(query *gorm.DB, column string, from, to *time.Time) *gorm.DB {
+	if from != nil {
+		query = query.Where(column+" >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where(column+" <= ?", *to)
+	}
+	return query
+}
+
+// whereMinAmount restricts a query to the records whose amount in column is at least min. Amounts are
+// stored as decimal strings without leading zeros, so they compare by length first and then as text.
+func whereMinAmount(query *gorm.DB, column, min string) (*gorm.DB, error) {
+	if min == "" {
+		return query, nil
+	}
+	amount, ok := new(big.Int).SetString(min, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", min)
+	}
+	value := amount.String()
+	return query.Where(fmt.Sprintf("LENGTH(%s) > ? OR (LENGTH(%s) = ? AND %s >= ?)", column, column, column),
+		len(value), len(value), value), nil
+}
+
+// isAddress reports whether a nullable address is the given address
+func isAddress(address *string, want string) bool {
+	return address != nil && *address == want
+}
+
+// Matches reports whether a transaction passes the filter, for transactions that are not queried
+// from the database such as newly indexed ones
+func (filter TransactionFilter) Matches(tx *Transaction) bool {
+	if filter.FromBlock != nil && tx.BlockNumber < *filter.FromBlock {
+		return false
+	}
+	if filter.ToBlock != nil && tx.BlockNumber > *filter.ToBlock {
+		return false
+	}
+	if filter.FromTime != nil && tx.Timestamp.Before(*filter.FromTime) {
+		return false
+	}
+	if filter.ToTime != nil && tx.Timestamp.After(*filter.ToTime) {
+		return false
+	}
+	if filter.Address != "" {
+		address := normalizeAddress(filter.Address)
+		if tx.FromAddress != address && !isAddress(tx.ToAddress, address) {
+			return false
+		}
+	}
+	if filter.FromAddress != "" && tx.FromAddress != normalizeAddress(filter.FromAddress) {
+		return false
+	}
+	if filter.ToAddress != "" && !isAddress(tx.ToAddress, normalizeAddress(filter.ToAddress)) {
+		return false
+	}
+	if filter.MinValue != "" {
+		min, ok := new(big.Int).SetString(filter.MinValue, 10)
+		value, valid := new(big.Int).SetString(tx.Value, 10)
+		if !ok || !valid || value.Cmp(min) < 0 {
+			return false
+		}
+	}
+	return true
+}
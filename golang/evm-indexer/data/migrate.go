@@ -0,0 +1,95 @@
+package data
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/synkube/app/evm-indexer/config"
+	"gorm.io/gorm"
+)
+
+// legacyChainID returns the chain ID of the records of tables created before records were keyed by
+// chain ID: those databases indexed a single chain, which must be the only configured chain.
+func legacyChainID(cfg *config.Config) (uint64, error) {
+	chains := cfg.IndexedChains()
+	if len(chains) != 1 || chains[0].ID <= 0 {
+		return 0, fmt.Errorf("the database has tables without chain IDs: configure their chain, with its id, as the only chain to migrate them, or set dbConfig.clean to start over")
+	}
+	return uint64(chains[0].ID), nil
+}
+
+// migrateChainIDs moves the rows of tables created before records were keyed by chain ID into
+// tables with the current primary keys, as AutoMigrate adds the chain_id column but does not change
+// primary keys.
+func migrateChainIDs(db *gorm.DB, cfg *config.Config) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		if !db.Migrator().HasTable(table) || db.Migrator().HasColumn(model, "chain_id") {
+			continue
+		}
+
+		id, err := legacyChainID(cfg)
+		if err != nil {
+			return err
+		}
+		log.Printf("Migrating table %s to chain ID %d", table, id)
+		migrate := func(tx *gorm.DB) error {
+			return migrateTableChainID(tx, stmt, model, id)
+		}
+		if db.Dialector.Name() != clickhouseDialect {
+			err = db.Transaction(migrate)
+		} else {
+			err = migrate(db)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to migrate table %s: %v", table, err)
+		}
+	}
+	return nil
+}
+
+// migrateTableChainID copies the rows of a table into a new table stamped with chainID, and
+// replaces the table with it
+func migrateTableChainID(db *gorm.DB, stmt *gorm.Statement, model interface{}, chainID uint64) error {
+	table := stmt.Schema.Table
+	migrated := table + "_chain_id"
+
+	// Index names are shared by the database, the new table takes them over
+	for _, index := range stmt.Schema.ParseIndexes() {
+		if db.Migrator().HasIndex(model, index.Name) {
+			if err := db.Migrator().DropIndex(model, index.Name); err != nil {
+				return err
+			}
+		}
+	}
+	if err := db.Table(migrated).Migrator().CreateTable(model); err != nil {
+		return err
+	}
+
+	columnTypes, err := db.Migrator().ColumnTypes(model)
+	if err != nil {
+		return err
+	}
+	columns := []string{stmt.Quote("chain_id")}
+	for _, columnType := range columnTypes {
+		if _, ok := stmt.Schema.FieldsByDBName[columnType.Name()]; ok && columnType.Name() != "chain_id" {
+			columns = append(columns, stmt.Quote(columnType.Name()))
+		}
+	}
+	list := strings.Join(columns, ", ")
+	copyRows := fmt.Sprintf("INSERT INTO %s (%s) SELECT %d, %s FROM %s",
+		stmt.Quote(migrated), list, chainID, strings.Join(columns[1:], ", "), stmt.Quote(table))
+	if err := db.Exec(copyRows).Error; err != nil {
+		return err
+	}
+
+	if err := db.Migrator().DropTable(table); err != nil {
+		return err
+	}
+	return db.Migrator().RenameTable(migrated, table)
+}
@@ -0,0 +1,68 @@
+package data
+
+import (
+	"testing"
+
+	coreData "github.com/synkube/app/core/data"
+	"github.com/synkube/app/evm-indexer/config"
+)
+
+// legacyTransaction is a transaction as stored before records were keyed by chain ID
+type legacyTransaction struct {
+	ID          string `gorm:"primaryKey"`
+	BlockHash   string `gorm:"index"`
+	FromAddress string
+	Value       string
+}
+
+func (legacyTransaction) TableName() string {
+	return "transactions"
+}
+
+func TestMigrateChainIDs(t *testing.T) {
+	dbConfig := coreData.DbConfig{
+		Type:   "sqlite",
+		SQLite: coreData.SQLiteConfig{File: "file:TestMigrateChainIDs?mode=memory&cache=shared"},
+	}
+	legacy := coreData.NewDataStore(dbConfig)
+	sqlDB, err := legacy.DB().DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if err := legacy.DB().AutoMigrate(&legacyTransaction{}); err != nil {
+		t.Fatal(err)
+	}
+	rows := []*legacyTransaction{{ID: "0x01", BlockHash: "0xb1", Value: "1"}, {ID: "0x02", BlockHash: "0xb1", Value: "2"}}
+	if err := legacy.DB().Create(rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a single configured chain the rows cannot be attributed
+	if err := migrateChainIDs(legacy.DB(), &config.Config{}); err == nil {
+		t.Fatal("migrated tables without knowing their chain")
+	}
+
+	cfg := &config.Config{DbConfig: dbConfig, Chains: []coreData.Chain{{ID: 5}}}
+	bds := NewBlockchainDataStore(Initialize(cfg), 0)
+
+	transactions, err := bds.ForChain(5).GetTransactionsByBlockHashes([]string{"0xb1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 2 || transactions[0].Value != "1" || transactions[1].Value != "2" {
+		t.Fatalf("migrated transactions = %+v, want both rows on chain 5", transactions)
+	}
+
+	// The same transaction ID can now be stored for another chain
+	if err := bds.ForChain(6).SaveTransaction(&Transaction{ID: "0x01", BlockHash: "0xb1"}); err != nil {
+		t.Fatalf("saving a transaction of another chain: %v", err)
+	}
+	transactions, err = bds.GetTransactionsByBlockHashes([]string{"0xb1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 3 {
+		t.Errorf("stored %d transactions, want 3", len(transactions))
+	}
+}
@@ -0,0 +1,246 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPageSize is the number of records returned when no page size is requested
+	DefaultPageSize = 100
+	// MaxPageSize is the largest number of records returned in a single page
+	MaxPageSize = 1000
+	// MaxBlockSpan is the largest number of blocks a block range query may cover
+	MaxBlockSpan = 10000
+)
+
+// ErrInvalidCursor is returned for cursors that were not built by this package
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageSize clamps a requested page size to the supported range
+func pageSize(first int) int {
+	if first <= 0 {
+		return DefaultPageSize
+	}
+	if first > MaxPageSize {
+		return MaxPageSize
+	}
+	return first
+}
+
+// BlockRange is an inclusive range of block numbers
+type BlockRange struct {
+	From uint64
+	To   uint64
+}
+
+// checkBlockSpan validates a block range requested by a query
+func checkBlockSpan(startBlock, endBlock uint64) error {
+	if endBlock < startBlock {
+		return fmt.Errorf("end block %d is before start block %d", endBlock, startBlock)
+	}
+	if endBlock-startBlock >= MaxBlockSpan {
+		return fmt.Errorf("block range %d-%d spans more than %d blocks", startBlock, endBlock, MaxBlockSpan)
+	}
+	return nil
+}
+
+// CompressBlockRanges merges ascending block numbers into ranges of consecutive blocks
+func CompressBlockRanges(blocks []int) []BlockRange {
+	ranges := make([]BlockRange, 0)
+	for _, block := range blocks {
+		number := uint64(block)
+		if last := len(ranges) - 1; last >= 0 && ranges[last].To+1 == number {
+			ranges[last].To = number
+			continue
+		}
+		ranges = append(ranges, BlockRange{From: number, To: number})
+	}
+	return ranges
+}
+
+// encodeCursor builds an opaque cursor from the ordering position of a record
+func encodeCursor(position ...interface{}) string {
+	parts := make([]string, len(position))
+	for i, p := range position {
+		parts[i] = fmt.Sprint(p)
+	}
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join(parts, ":")))
+}
+
+// decodeCursorFields splits a cursor built by encodeCursor into the expected number of parts
+func decodeCursorFields(cursor string, parts int) ([]string, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidCursor, cursor, err)
+	}
+	fields := strings.Split(string(raw), ":")
+	if len(fields) != parts {
+		return nil, fmt.Errorf("%w %q", ErrInvalidCursor, cursor)
+	}
+	return fields, nil
+}
+
+// decodeCursor parses a cursor made only of numeric parts
+func decodeCursor(cursor string, parts int) ([]uint64, error) {
+	fields, err := decodeCursorFields(cursor, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	position := make([]uint64, parts)
+	for i, field := range fields {
+		position[i], err = strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidCursor, cursor, err)
+		}
+	}
+	return position, nil
+}
+
+// Edge is a record of a page with the cursor pointing at it
+type Edge[T any] struct {
+	Node   T
+	Cursor string
+}
+
+// Page is a page of records of a connection
+type Page[T any] struct {
+	Edges       []Edge[T]
+	TotalCount  int64 // Number of records matching the filters, across all pages
+	HasNextPage bool
+}
+
+// EndCursor returns the cursor of the last record of the page, or an empty string for an empty page
+func (page *Page[T]) EndCursor() string {
+	if len(page.Edges) == 0 {
+		return ""
+	}
+	return page.Edges[len(page.Edges)-1].Cursor
+}
+
+// keyColumn is a column of a keyset
+type keyColumn struct {
+	name    string
+	numeric bool
+}
+
+// keyset is a sort order over columns that together identify a record, with the values of those
+// columns for a record. Pages are fetched after the values of the last record of the previous page,
+// which stays fast however deep the page is.
+type keyset[T any] struct {
+	columns []keyColumn
+	values  func(T) []interface{}
+	desc    bool
+}
+
+// order returns the ORDER BY clause of the keyset
+func (k keyset[T]) order() string {
+	direction := "ASC"
+	if k.desc {
+		direction = "DESC"
+	}
+	parts := make([]string, len(k.columns))
+	for i, column := range k.columns {
+		parts[i] = column.name + " " + direction
+	}
+	return strings.Join(parts, ", ")
+}
+
+// after restricts a query to the records after a cursor built from the values of the keyset columns
+func (k keyset[T]) after(query *gorm.DB, cursor string) (*gorm.DB, error) {
+	fields, err := decodeCursorFields(cursor, len(k.columns))
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i] = field
+		if k.columns[i].numeric {
+			if values[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+				return nil, fmt.Errorf("%w %q: %v", ErrInvalidCursor, cursor, err)
+			}
+		}
+	}
+
+	// (a > x) OR (a = x AND b > y) OR ... over the columns in order
+	comparison := ">"
+	if k.desc {
+		comparison = "<"
+	}
+	conditions := make([]string, len(k.columns))
+	args := make([]interface{}, 0)
+	for i, column := range k.columns {
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, k.columns[j].name+" = ?")
+			args = append(args, values[j])
+		}
+		terms = append(terms, column.name+" "+comparison+" ?")
+		args = append(args, values[i])
+		conditions[i] = "(" + strings.Join(terms, " AND ") + ")"
+	}
+	return query.Where(strings.Join(conditions, " OR "), args...), nil
+}
+
+// paginate fetches up to first records matched by query after the cursor, in keyset order, and
+// counts all records matched by query.
+func paginate[T any](query *gorm.DB, k keyset[T], first int, after string) (*Page[T], error) {
+	page := &Page[T]{Edges: make([]Edge[T], 0)}
+	if err := query.Session(&gorm.Session{}).Count(&page.TotalCount).Error; err != nil {
+		return nil, err
+	}
+
+	if after != "" {
+		var err error
+		if query, err = k.after(query, after); err != nil {
+			return nil, err
+		}
+	}
+	limit := pageSize(first)
+	var records []T
+	if err := query.Order(k.order()).Limit(limit + 1).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	page.addEdges(records, k, limit)
+	return page, nil
+}
+
+// addEdges adds up to limit records to the page, records fetched beyond limit tell there is a next page
+func (page *Page[T]) addEdges(records []T, k keyset[T], limit int) {
+	if len(records) > limit {
+		records = records[:limit]
+		page.HasNextPage = true
+	}
+	for _, record := range records {
+		page.Edges = append(page.Edges, Edge[T]{Node: record, Cursor: encodeCursor(k.values(record)...)})
+	}
+}
+
+// list fetches up to limit records matched by query in keyset order, skipping the first offset
+func list[T any](query *gorm.DB, k keyset[T], offset, limit int) ([]T, error) {
+	var records []T
+	if err := query.Order(k.order()).Offset(offset).Limit(limit).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// normalizeAddress converts an address to the checksummed form it is stored in
+func normalizeAddress(address string) string {
+	return common.HexToAddress(address).Hex()
+}
+
+// normalizeHash converts a hash to the lowercase form with 0x prefix it is stored in
+func normalizeHash(hash string) string {
+	if len(hash) >= 2 && hash[0] == '0' && (hash[1] == 'x' || hash[1] == 'X') {
+		hash = hash[2:]
+	}
+	return "0x" + strings.ToLower(hash)
+}
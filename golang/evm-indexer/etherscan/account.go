@@ -0,0 +1,309 @@
+package etherscan
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+// Token standards listed by the token transfer actions
+const (
+	erc20  = "ERC20"
+	erc721 = "ERC721"
+)
+
+// Transaction is a transaction as listed by account.txlist
+type Transaction struct {
+	BlockNumber       string `json:"blockNumber"`
+	TimeStamp         string `json:"timeStamp"`
+	Hash              string `json:"hash"`
+	Nonce             string `json:"nonce"`
+	BlockHash         string `json:"blockHash"`
+	TransactionIndex  string `json:"transactionIndex"`
+	From              string `json:"from"`
+	To                string `json:"to"`
+	Value             string `json:"value"`
+	Gas               string `json:"gas"`
+	GasPrice          string `json:"gasPrice"`
+	IsError           string `json:"isError"`
+	TxReceiptStatus   string `json:"txreceipt_status"`
+	Input             string `json:"input"`
+	ContractAddress   string `json:"contractAddress"`
+	CumulativeGasUsed string `json:"cumulativeGasUsed"`
+	GasUsed           string `json:"gasUsed"`
+	Confirmations     string `json:"confirmations"`
+	MethodID          string `json:"methodId"`
+	FunctionName      string `json:"functionName"`
+}
+
+// TokenTransfer is a transfer as listed by account.tokentx and account.tokennfttx
+type TokenTransfer struct {
+	BlockNumber       string `json:"blockNumber"`
+	TimeStamp         string `json:"timeStamp"`
+	Hash              string `json:"hash"`
+	Nonce             string `json:"nonce"`
+	BlockHash         string `json:"blockHash"`
+	From              string `json:"from"`
+	ContractAddress   string `json:"contractAddress"`
+	To                string `json:"to"`
+	Value             string `json:"value,omitempty"`
+	TokenID           string `json:"tokenID,omitempty"`
+	TokenName         string `json:"tokenName"`
+	TokenSymbol       string `json:"tokenSymbol"`
+	TokenDecimal      string `json:"tokenDecimal"`
+	TransactionIndex  string `json:"transactionIndex"`
+	Gas               string `json:"gas"`
+	GasPrice          string `json:"gasPrice"`
+	GasUsed           string `json:"gasUsed"`
+	CumulativeGasUsed string `json:"cumulativeGasUsed"`
+	Input             string `json:"input"`
+	Confirmations     string `json:"confirmations"`
+}
+
+// txList serves account.txlist, the transactions sent or received by an address
+func txList(r *request) (interface{}, error) {
+	address, err := r.address("address")
+	if err != nil {
+		return nil, err
+	}
+	filter := data.TransactionFilter{Address: address}
+	if filter.FromBlock, err = r.block("startblock"); err != nil {
+		return nil, err
+	}
+	if filter.ToBlock, err = r.block("endblock"); err != nil {
+		return nil, err
+	}
+	desc, err := r.desc()
+	if err != nil {
+		return nil, err
+	}
+	offset, limit, err := r.page(maxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	order := data.TransactionOrder{Field: data.TransactionOrderBlock, Desc: desc}
+	transactions, err := r.bds.ListTransactions(filter, order, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, notFound("No transactions found")
+	}
+
+	hashes := make([]string, 0, len(transactions))
+	for _, tx := range transactions {
+		hashes = append(hashes, tx.ID)
+	}
+	receipts, err := r.receipts(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmations := newConfirmations(r.bds)
+	result := make([]*Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		confirmed, err := confirmations.of(tx.ChainID, tx.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		item := &Transaction{
+			BlockNumber:      strconv.FormatUint(tx.BlockNumber, 10),
+			TimeStamp:        strconv.FormatInt(tx.Timestamp.Unix(), 10),
+			Hash:             strings.ToLower(tx.ID),
+			Nonce:            strconv.FormatUint(tx.Nonce, 10),
+			BlockHash:        strings.ToLower(tx.BlockHash),
+			TransactionIndex: strconv.FormatUint(tx.TransactionIndex, 10),
+			From:             strings.ToLower(tx.FromAddress),
+			To:               recipient(tx.ToAddress),
+			Value:            tx.Value,
+			Gas:              strconv.FormatUint(tx.Gas, 10),
+			GasPrice:         tx.GasPrice,
+			Input:            "0x" + tx.InputData,
+			Confirmations:    confirmed,
+			MethodID:         methodID(tx.InputData),
+		}
+		if receipt, ok := receipts[receiptKey{tx.ChainID, tx.ID}]; ok {
+			item.IsError = "0"
+			item.TxReceiptStatus = strconv.FormatUint(receipt.Status, 10)
+			if receipt.Status == 0 {
+				item.IsError = "1"
+			}
+			item.ContractAddress = strings.ToLower(receipt.ContractAddress)
+			item.CumulativeGasUsed = strconv.FormatUint(receipt.CumulativeGasUsed, 10)
+			item.GasUsed = strconv.FormatUint(receipt.GasUsed, 10)
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// balance serves account.balance, the balance of an address at the latest indexed block
+// involving it
+func balance(r *request) (interface{}, error) {
+	address, err := r.address("address")
+	if err != nil {
+		return nil, err
+	}
+	if tag := r.param("tag"); tag != "" && tag != "latest" {
+		return nil, paramError("Only the latest tag is supported")
+	}
+
+	accounts, err := r.bds.GetAccountsByAddresses([]string{address})
+	if err != nil {
+		return nil, err
+	}
+	// Without a chainid the most recently updated balance wins
+	var latest *data.Account
+	for _, account := range accounts {
+		if latest == nil || account.BalanceBlock > latest.BalanceBlock {
+			latest = account
+		}
+	}
+	if latest == nil {
+		return "0", nil
+	}
+	return latest.Balance, nil
+}
+
+// tokenTx serves the transfers of a token standard sent or received by an address, of a single
+// token when contractaddress is given
+func tokenTx(standard string) handler {
+	return func(r *request) (interface{}, error) {
+		address, err := r.optionalAddress("address")
+		if err != nil {
+			return nil, err
+		}
+		token, err := r.optionalAddress("contractaddress")
+		if err != nil {
+			return nil, err
+		}
+		if address == "" && token == "" {
+			return nil, paramError("Missing address or contractaddress")
+		}
+		filter := data.TokenTransferFilter{Address: address, Token: token, Standard: standard}
+		if filter.FromBlock, err = r.block("startblock"); err != nil {
+			return nil, err
+		}
+		if filter.ToBlock, err = r.block("endblock"); err != nil {
+			return nil, err
+		}
+		desc, err := r.desc()
+		if err != nil {
+			return nil, err
+		}
+		offset, limit, err := r.page(maxResults)
+		if err != nil {
+			return nil, err
+		}
+
+		transfers, err := r.bds.ListTokenTransfers(filter, desc, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(transfers) == 0 {
+			return nil, notFound("No transactions found")
+		}
+
+		hashes := make([]string, 0, len(transfers))
+		for _, transfer := range transfers {
+			hashes = append(hashes, transfer.TransactionHash)
+		}
+		transactions, err := r.transactions(hashes)
+		if err != nil {
+			return nil, err
+		}
+		receipts, err := r.receipts(hashes)
+		if err != nil {
+			return nil, err
+		}
+
+		confirmations := newConfirmations(r.bds)
+		result := make([]*TokenTransfer, 0, len(transfers))
+		for _, transfer := range transfers {
+			confirmed, err := confirmations.of(transfer.ChainID, transfer.BlockNumber)
+			if err != nil {
+				return nil, err
+			}
+			item := &TokenTransfer{
+				BlockNumber:     strconv.FormatUint(transfer.BlockNumber, 10),
+				TimeStamp:       strconv.FormatInt(transfer.Timestamp.Unix(), 10),
+				Hash:            strings.ToLower(transfer.TransactionHash),
+				BlockHash:       strings.ToLower(transfer.BlockHash),
+				From:            strings.ToLower(transfer.FromAddress),
+				ContractAddress: strings.ToLower(transfer.TokenAddress),
+				To:              strings.ToLower(transfer.ToAddress),
+				Confirmations:   confirmed,
+			}
+			if standard == erc20 {
+				item.Value = transfer.Amount
+			} else {
+				item.TokenID = transfer.TokenID
+			}
+			key := receiptKey{transfer.ChainID, transfer.TransactionHash}
+			if tx, ok := transactions[key]; ok {
+				item.Nonce = strconv.FormatUint(tx.Nonce, 10)
+				item.TransactionIndex = strconv.FormatUint(tx.TransactionIndex, 10)
+				item.Gas = strconv.FormatUint(tx.Gas, 10)
+				item.GasPrice = tx.GasPrice
+				item.Input = "0x" + tx.InputData
+			}
+			if receipt, ok := receipts[key]; ok {
+				item.GasUsed = strconv.FormatUint(receipt.GasUsed, 10)
+				item.CumulativeGasUsed = strconv.FormatUint(receipt.CumulativeGasUsed, 10)
+			}
+			result = append(result, item)
+		}
+		return result, nil
+	}
+}
+
+// recipient returns the lowercase recipient of a transaction, empty for contract deployments like
+// Etherscan
+func recipient(to *string) string {
+	if to == nil {
+		return ""
+	}
+	return strings.ToLower(*to)
+}
+
+// methodID returns the selector of the calldata of a transaction, empty for plain transfers
+func methodID(input string) string {
+	if len(input) < 8 {
+		return "0x"
+	}
+	return "0x" + input[:8]
+}
+
+// receiptKey identifies the receipt or transaction of a hash on a chain
+type receiptKey struct {
+	chainID uint64
+	hash    string
+}
+
+// receipts retrieves the receipts of transactions in one query
+func (r *request) receipts(hashes []string) (map[receiptKey]*data.Receipt, error) {
+	receipts, err := r.bds.GetReceiptsByTransactionHashes(hashes)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[receiptKey]*data.Receipt, len(receipts))
+	for _, receipt := range receipts {
+		result[receiptKey{receipt.ChainID, receipt.TransactionHash}] = receipt
+	}
+	return result, nil
+}
+
+// transactions retrieves transactions by hash in one query
+func (r *request) transactions(hashes []string) (map[receiptKey]*data.Transaction, error) {
+	transactions, err := r.bds.GetTransactionsByIDs(hashes)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[receiptKey]*data.Transaction, len(transactions))
+	for _, tx := range transactions {
+		result[receiptKey{tx.ChainID, tx.ID}] = tx
+	}
+	return result, nil
+}
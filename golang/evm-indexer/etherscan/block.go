@@ -0,0 +1,87 @@
+package etherscan
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlockReward is a block as returned by block.getblockreward
+type BlockReward struct {
+	BlockNumber          string        `json:"blockNumber"`
+	TimeStamp            string        `json:"timeStamp"`
+	BlockMiner           string        `json:"blockMiner"`
+	BlockReward          string        `json:"blockReward"`
+	Uncles               []interface{} `json:"uncles"`
+	UncleInclusionReward string        `json:"uncleInclusionReward"`
+}
+
+// blockReward serves block.getblockreward. The reward is the priority fees paid to the miner, the
+// static reward of pre merge blocks is not indexed and uncles are not tracked.
+func blockReward(r *request) (interface{}, error) {
+	number, err := r.block("blockno")
+	if err != nil {
+		return nil, err
+	}
+	if number == nil {
+		return nil, paramError("Missing blockno")
+	}
+	block, err := r.bds.GetBlockByNumber(*number)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, paramError("Block number not indexed")
+	}
+
+	receipts, err := r.bds.ForChain(block.ChainID).GetReceiptsByBlockHash(block.Hash)
+	if err != nil {
+		return nil, err
+	}
+	baseFee := new(big.Int)
+	if block.BaseFee != "" {
+		if _, ok := baseFee.SetString(block.BaseFee, 10); !ok {
+			return nil, fmt.Errorf("invalid base fee %q of block %d", block.BaseFee, block.Number)
+		}
+	}
+	reward := new(big.Int)
+	for _, receipt := range receipts {
+		price, ok := new(big.Int).SetString(receipt.EffectiveGasPrice, 10)
+		if !ok {
+			continue
+		}
+		fee := price.Sub(price, baseFee)
+		reward.Add(reward, fee.Mul(fee, new(big.Int).SetUint64(receipt.GasUsed)))
+	}
+
+	return &BlockReward{
+		BlockNumber:          strconv.FormatUint(block.Number, 10),
+		TimeStamp:            strconv.FormatInt(block.Timestamp.Unix(), 10),
+		BlockMiner:           strings.ToLower(block.Miner),
+		BlockReward:          reward.String(),
+		Uncles:               []interface{}{},
+		UncleInclusionReward: "0",
+	}, nil
+}
+
+// blockByTime serves block.getblocknobytime, the block closest to a unix timestamp
+func blockByTime(r *request) (interface{}, error) {
+	timestamp, err := strconv.ParseInt(r.param("timestamp"), 10, 64)
+	if err != nil {
+		return nil, paramError("Invalid timestamp")
+	}
+	closest := r.param("closest")
+	if closest != "before" && closest != "after" {
+		return nil, paramError("Invalid closest, expected before or after")
+	}
+	block, err := r.bds.GetBlockByTime(time.Unix(timestamp, 0), closest)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, paramError("No closest block found")
+	}
+	return strconv.FormatUint(block.Number, 10), nil
+}
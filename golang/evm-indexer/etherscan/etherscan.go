@@ -0,0 +1,211 @@
+package etherscan
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+// maxResults is the largest page * offset a list action accepts, as on Etherscan
+const maxResults = 10000
+
+// response is the envelope of every Etherscan response. Status is "1" on success, otherwise result
+// holds the error message, or an empty list when nothing matched.
+type response struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	Result  interface{} `json:"result"`
+}
+
+// paramError is an error caused by the request, returned to the client as is
+type paramError string
+
+func (e paramError) Error() string {
+	return string(e)
+}
+
+// notFound is a successful request that matched nothing
+type notFound string
+
+func (e notFound) Error() string {
+	return string(e)
+}
+
+// handler serves an action from the request parameters
+type handler func(r *request) (interface{}, error)
+
+var actions = map[string]handler{
+	"account.txlist":         txList,
+	"account.balance":        balance,
+	"account.tokentx":        tokenTx(erc20),
+	"account.tokennfttx":     tokenTx(erc721),
+	"block.getblockreward":   blockReward,
+	"block.getblocknobytime": blockByTime,
+	"logs.getLogs":           getLogs,
+}
+
+// RegisterRoutes adds the Etherscan compatible /api endpoint to r
+func RegisterRoutes(r gin.IRouter, bds *data.BlockchainDataStore) {
+	serve := func(c *gin.Context) {
+		handle(c, bds)
+	}
+	r.GET("/api", serve)
+	r.POST("/api", serve)
+}
+
+func handle(c *gin.Context, bds *data.BlockchainDataStore) {
+	req := &request{c: c, bds: bds}
+	action, ok := actions[req.param("module")+"."+req.param("action")]
+	if !ok {
+		c.JSON(http.StatusOK, response{Status: "0", Message: "NOTOK", Result: "Error! Missing Or invalid Module name or Action name"})
+		return
+	}
+	if chainID := req.param("chainid"); chainID != "" {
+		id, err := strconv.ParseUint(chainID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusOK, response{Status: "0", Message: "NOTOK", Result: "Error! Invalid chainid"})
+			return
+		}
+		req.bds = bds.ForChain(id)
+	}
+
+	result, err := action(req)
+	var missing notFound
+	var invalid paramError
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, response{Status: "1", Message: "OK", Result: result})
+	case errors.As(err, &missing):
+		c.JSON(http.StatusOK, response{Status: "0", Message: missing.Error(), Result: []interface{}{}})
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusOK, response{Status: "0", Message: "NOTOK", Result: "Error! " + invalid.Error()})
+	case errors.Is(err, data.ErrInvalidCursor):
+		c.JSON(http.StatusOK, response{Status: "0", Message: "NOTOK", Result: "Error! " + err.Error()})
+	case errors.Is(err, data.ErrChainRequired):
+		c.JSON(http.StatusOK, response{Status: "0", Message: "NOTOK", Result: "Error! Missing chainid, several chains are indexed"})
+	default:
+		log.Printf("Error serving Etherscan action %s.%s: %v", req.param("module"), req.param("action"), err)
+		c.JSON(http.StatusInternalServerError, response{Status: "0", Message: "NOTOK", Result: "Error! Internal error"})
+	}
+}
+
+// request reads the parameters of an Etherscan request, from the query or a form body
+type request struct {
+	c   *gin.Context
+	bds *data.BlockchainDataStore
+}
+
+func (r *request) param(name string) string {
+	if value, ok := r.c.GetQuery(name); ok {
+		return value
+	}
+	return r.c.PostForm(name)
+}
+
+// address returns a required address parameter
+func (r *request) address(name string) (string, error) {
+	address := r.param(name)
+	if !common.IsHexAddress(address) {
+		return "", paramError("Invalid address format")
+	}
+	return address, nil
+}
+
+// optionalAddress returns an address parameter that may be left out
+func (r *request) optionalAddress(name string) (string, error) {
+	if r.param(name) == "" {
+		return "", nil
+	}
+	return r.address(name)
+}
+
+// block returns an optional block number parameter, "latest" meaning no bound
+func (r *request) block(name string) (*uint64, error) {
+	value := r.param(name)
+	if value == "" || value == "latest" {
+		return nil, nil
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, paramError(fmt.Sprintf("Invalid %s", name))
+	}
+	return &number, nil
+}
+
+// uint returns an optional numeric parameter
+func (r *request) uint(name string, fallback uint64) (uint64, error) {
+	value := r.param(name)
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, paramError(fmt.Sprintf("Invalid %s", name))
+	}
+	return number, nil
+}
+
+// page returns the offset and limit of the requested page, limited to max records per page
+func (r *request) page(max uint64) (int, int, error) {
+	page, err := r.uint("page", 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := r.uint("offset", max)
+	if err != nil {
+		return 0, 0, err
+	}
+	if page == 0 {
+		page = 1
+	}
+	if size == 0 || size > max {
+		size = max
+	}
+	if page*size > maxResults {
+		return 0, 0, paramError(fmt.Sprintf("Result window is too large, PageNo x Offset size must be less than or equal to %d", maxResults))
+	}
+	return int((page - 1) * size), int(size), nil
+}
+
+// desc reports whether the sort parameter asks for descending order
+func (r *request) desc() (bool, error) {
+	switch r.param("sort") {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	default:
+		return false, paramError("Invalid sort order, expected asc or desc")
+	}
+}
+
+// confirmations counts the blocks indexed on top of a block, per chain of the request
+type confirmations struct {
+	bds    *data.BlockchainDataStore
+	latest map[uint64]uint64
+}
+
+func newConfirmations(bds *data.BlockchainDataStore) *confirmations {
+	return &confirmations{bds: bds, latest: make(map[uint64]uint64)}
+}
+
+func (c *confirmations) of(chainID, blockNumber uint64) (string, error) {
+	latest, ok := c.latest[chainID]
+	if !ok {
+		var err error
+		if latest, err = c.bds.ForChain(chainID).GetLatestSavedBlock(); err != nil {
+			return "", err
+		}
+		c.latest[chainID] = latest
+	}
+	if latest < blockNumber {
+		return "0", nil
+	}
+	return strconv.FormatUint(latest-blockNumber, 10), nil
+}
@@ -0,0 +1,114 @@
+package etherscan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+// maxLogs is the largest page of logs.getLogs
+const maxLogs = 1000
+
+// Log is a log as returned by logs.getLogs, numbers are hex encoded
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TimeStamp        string   `json:"timeStamp"`
+	GasPrice         string   `json:"gasPrice"`
+	GasUsed          string   `json:"gasUsed"`
+	LogIndex         string   `json:"logIndex"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+}
+
+// getLogs serves logs.getLogs. Topics are always combined with and.
+func getLogs(r *request) (interface{}, error) {
+	address, err := r.optionalAddress("address")
+	if err != nil {
+		return nil, err
+	}
+	filter := data.LogFilter{Address: address}
+	if filter.FromBlock, err = r.block("fromBlock"); err != nil {
+		return nil, err
+	}
+	if filter.ToBlock, err = r.block("toBlock"); err != nil {
+		return nil, err
+	}
+	for i := range filter.Topics {
+		filter.Topics[i] = r.param(fmt.Sprintf("topic%d", i))
+		for j := i + 1; j < len(filter.Topics); j++ {
+			if opr := r.param(fmt.Sprintf("topic%d_%d_opr", i, j)); opr != "" && opr != "and" {
+				return nil, paramError("Only the and topic operator is supported")
+			}
+		}
+	}
+	offset, limit, err := r.page(maxLogs)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := r.bds.ListLogs(filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, notFound("No records found")
+	}
+
+	blockHashes := make([]string, 0, len(logs))
+	txHashes := make([]string, 0, len(logs))
+	for _, l := range logs {
+		blockHashes = append(blockHashes, l.BlockHash)
+		txHashes = append(txHashes, l.TransactionHash)
+	}
+	blocks, err := r.bds.GetBlocksByHashes(blockHashes)
+	if err != nil {
+		return nil, err
+	}
+	timestamps := make(map[receiptKey]int64, len(blocks))
+	for _, block := range blocks {
+		timestamps[receiptKey{block.ChainID, block.Hash}] = block.Timestamp.Unix()
+	}
+	receipts, err := r.receipts(txHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Log, 0, len(logs))
+	for _, l := range logs {
+		item := &Log{
+			Address:          strings.ToLower(l.Address),
+			Topics:           []string{},
+			Data:             "0x" + l.Data,
+			BlockNumber:      hexUint(l.BlockNumber),
+			BlockHash:        l.BlockHash,
+			TimeStamp:        hexUint(uint64(timestamps[receiptKey{l.ChainID, l.BlockHash}])),
+			LogIndex:         hexUint(l.LogIndex),
+			TransactionHash:  l.TransactionHash,
+			TransactionIndex: hexUint(l.TransactionIndex),
+		}
+		for _, topic := range []string{l.Topic0, l.Topic1, l.Topic2, l.Topic3} {
+			if topic != "" {
+				item.Topics = append(item.Topics, topic)
+			}
+		}
+		if receipt, ok := receipts[receiptKey{l.ChainID, l.TransactionHash}]; ok {
+			item.GasUsed = hexUint(receipt.GasUsed)
+			if price, err := strconv.ParseUint(receipt.EffectiveGasPrice, 10, 64); err == nil {
+				item.GasPrice = hexUint(price)
+			}
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// hexUint encodes a number the way Etherscan does in logs
+func hexUint(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
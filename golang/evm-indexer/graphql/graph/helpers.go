@@ -0,0 +1,340 @@
+package graph
+
+// Helpers of the resolvers: conversions to the GraphQL models, argument parsing and connections.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/synkube/app/evm-indexer/data"
+	"github.com/synkube/app/evm-indexer/graphql/graph/model"
+)
+
+func mapBlockToModel(block *data.Block) *model.Block {
+	return &model.Block{
+		ChainID:         fmt.Sprint(block.ChainID),
+		ID:              block.ID,
+		Hash:            block.Hash,
+		Number:          fmt.Sprint(block.Number),
+		Timestamp:       block.Timestamp.String(),
+		NumberOfTxs:     fmt.Sprint(block.NumberOfTxs),
+		Miner:           block.Miner,
+		ParentHash:      block.ParentHash,
+		Difficulty:      block.Difficulty,
+		TotalDifficulty: block.TotalDifficulty,
+		Size:            fmt.Sprint(block.Size),
+		GasUsed:         fmt.Sprint(block.GasUsed),
+		GasLimit:        fmt.Sprint(block.GasLimit),
+		BaseFee:         optionalString(block.BaseFee),
+		Nonce:           block.Nonce,
+		ExtraData:       block.ExtraData,
+	}
+}
+func mapTransactionToModel(tx *data.Transaction) *model.Transaction {
+	blobHashes := []string{}
+	if tx.BlobHashes != "" {
+		if err := json.Unmarshal([]byte(tx.BlobHashes), &blobHashes); err != nil {
+			log.Printf("Invalid blob hashes of transaction %s: %v", tx.ID, err)
+		}
+	}
+	return &model.Transaction{
+		ChainID:              fmt.Sprint(tx.ChainID),
+		ID:                   tx.ID,
+		BlockHash:            tx.BlockHash,
+		BlockNumber:          fmt.Sprint(tx.BlockNumber),
+		FromAddress:          tx.FromAddress,
+		ToAddress:            tx.ToAddress, // Null for contract deployments
+		Value:                tx.Value,
+		Gas:                  fmt.Sprint(tx.Gas),
+		GasPrice:             tx.GasPrice,
+		InputData:            tx.InputData,
+		Nonce:                fmt.Sprint(tx.Nonce),
+		TransactionIndex:     fmt.Sprint(tx.TransactionIndex),
+		Timestamp:            tx.Timestamp.String(),
+		Type:                 int(tx.Type),
+		MaxFeePerGas:         optionalString(tx.MaxFeePerGas),
+		MaxPriorityFeePerGas: optionalString(tx.MaxPriorityFeePerGas),
+		AccessList:           optionalString(tx.AccessList),
+		BlobHashes:           blobHashes,
+		CreatedContract:      optionalString(tx.CreatedContract),
+	}
+}
+func mapAccountToModel(account *data.Account) *model.Account {
+	return &model.Account{
+		ChainID:      fmt.Sprint(account.ChainID),
+		Address:      account.Address,
+		Balance:      account.Balance,
+		BalanceBlock: fmt.Sprint(account.BalanceBlock),
+	}
+}
+func mapAccountBalanceToModel(balance *data.AccountBalance) *model.AccountBalance {
+	return &model.AccountBalance{
+		ChainID:     fmt.Sprint(balance.ChainID),
+		Address:     balance.Address,
+		BlockNumber: fmt.Sprint(balance.BlockNumber),
+		BlockHash:   balance.BlockHash,
+		Balance:     balance.Balance,
+	}
+}
+func mapContractToModel(contract *data.Contract) *model.Contract {
+	return &model.Contract{
+		ChainID:         fmt.Sprint(contract.ChainID),
+		Address:         contract.Address,
+		Cursor:          data.ContractCursor(contract),
+		Creator:         contract.Creator,
+		TransactionHash: contract.TransactionHash,
+		BlockHash:       contract.BlockHash,
+		BlockNumber:     fmt.Sprint(contract.BlockNumber),
+		BytecodeHash:    contract.BytecodeHash,
+		Timestamp:       contract.Timestamp.String(),
+	}
+}
+func mapInternalTransactionToModel(internalTx *data.InternalTransaction) *model.InternalTransaction {
+	return &model.InternalTransaction{
+		ChainID:         fmt.Sprint(internalTx.ChainID),
+		ID:              internalTx.ID,
+		TransactionHash: internalTx.TransactionHash,
+		BlockNumber:     fmt.Sprint(internalTx.BlockNumber),
+		TraceIndex:      fmt.Sprint(internalTx.TraceIndex),
+		Type:            internalTx.Type,
+		FromAddress:     internalTx.FromAddress,
+		ToAddress:       internalTx.ToAddress,
+		Value:           internalTx.Value,
+		Gas:             fmt.Sprint(internalTx.Gas),
+		GasUsed:         fmt.Sprint(internalTx.GasUsed),
+		Depth:           fmt.Sprint(internalTx.Depth),
+		Error:           optionalString(internalTx.Error),
+	}
+}
+func mapTokenTransferToModel(transfer *data.TokenTransfer) *model.TokenTransfer {
+	return &model.TokenTransfer{
+		ChainID:         fmt.Sprint(transfer.ChainID),
+		ID:              transfer.ID,
+		Cursor:          data.TokenTransferCursor(transfer),
+		BlockHash:       transfer.BlockHash,
+		BlockNumber:     fmt.Sprint(transfer.BlockNumber),
+		TransactionHash: transfer.TransactionHash,
+		LogIndex:        fmt.Sprint(transfer.LogIndex),
+		TokenAddress:    transfer.TokenAddress,
+		Standard:        transfer.Standard,
+		Operator:        optionalString(transfer.Operator),
+		FromAddress:     transfer.FromAddress,
+		ToAddress:       transfer.ToAddress,
+		Amount:          transfer.Amount,
+		TokenID:         optionalString(transfer.TokenID),
+		Timestamp:       transfer.Timestamp.String(),
+	}
+}
+func mapDecodedEventToModel(event *data.DecodedEvent) *model.DecodedEvent {
+	result := &model.DecodedEvent{
+		ChainID:         fmt.Sprint(event.ChainID),
+		ID:              event.ID,
+		Cursor:          data.DecodedEventCursor(event),
+		BlockHash:       event.BlockHash,
+		BlockNumber:     fmt.Sprint(event.BlockNumber),
+		TransactionHash: event.TransactionHash,
+		ContractAddress: event.ContractAddress,
+		ContractName:    event.ContractName,
+		Kind:            event.Kind,
+		Name:            event.Name,
+		Signature:       event.Signature,
+		Args:            event.Args,
+		Timestamp:       event.Timestamp.String(),
+	}
+	if event.Kind == data.DecodedKindEvent {
+		result.LogIndex = optionalString(fmt.Sprint(event.LogIndex))
+	}
+	return result
+}
+func mapFailedBlockToModel(failed *data.FailedBlock) *model.FailedBlock {
+	return &model.FailedBlock{
+		ChainID:  fmt.Sprint(failed.ChainID),
+		Number:   fmt.Sprint(failed.Number),
+		Error:    failed.Error,
+		Attempts: failed.Attempts,
+		FailedAt: failed.FailedAt.String(),
+	}
+}
+func mapIndexerStateToModel(state *data.IndexerState) *model.IndexerState {
+	return &model.IndexerState{
+		ChainID:       fmt.Sprint(state.ChainID),
+		LowWaterMark:  fmt.Sprint(state.LowWaterMark),
+		HighWaterMark: fmt.Sprint(state.HighWaterMark),
+		UpdatedAt:     state.UpdatedAt.String(),
+	}
+}
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+func derefInt(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+func mapPageInfo(hasNextPage bool, endCursor string) *model.PageInfo {
+	return &model.PageInfo{HasNextPage: hasNextPage, EndCursor: optionalString(endCursor)}
+}
+func isDesc(direction *model.OrderDirection) bool {
+	return direction != nil && *direction == model.OrderDirectionDesc
+}
+func blockFilter(filter *model.BlockFilter) (data.BlockFilter, error) {
+	if filter == nil {
+		return data.BlockFilter{}, nil
+	}
+	var f data.BlockFilter
+	var err error
+	if f.FromBlock, err = parseOptionalBlock(filter.FromBlock); err != nil {
+		return f, err
+	}
+	if f.ToBlock, err = parseOptionalBlock(filter.ToBlock); err != nil {
+		return f, err
+	}
+	if f.FromTime, err = parseOptionalTime(filter.FromTime); err != nil {
+		return f, err
+	}
+	if f.ToTime, err = parseOptionalTime(filter.ToTime); err != nil {
+		return f, err
+	}
+	f.Miner = derefString(filter.Miner)
+	return f, nil
+}
+func transactionFilter(filter *model.TransactionFilter) (data.TransactionFilter, error) {
+	if filter == nil {
+		return data.TransactionFilter{}, nil
+	}
+	var f data.TransactionFilter
+	var err error
+	if f.FromBlock, err = parseOptionalBlock(filter.FromBlock); err != nil {
+		return f, err
+	}
+	if f.ToBlock, err = parseOptionalBlock(filter.ToBlock); err != nil {
+		return f, err
+	}
+	if f.FromTime, err = parseOptionalTime(filter.FromTime); err != nil {
+		return f, err
+	}
+	if f.ToTime, err = parseOptionalTime(filter.ToTime); err != nil {
+		return f, err
+	}
+	f.Address = derefString(filter.Address)
+	f.FromAddress = derefString(filter.FromAddress)
+	f.ToAddress = derefString(filter.ToAddress)
+	f.MinValue = derefString(filter.MinValue)
+	if _, ok := new(big.Int).SetString(f.MinValue, 10); f.MinValue != "" && !ok {
+		return f, fmt.Errorf("invalid minValue %q", f.MinValue)
+	}
+	return f, nil
+}
+func accountFilter(filter *model.AccountFilter) (data.AccountFilter, error) {
+	if filter == nil {
+		return data.AccountFilter{}, nil
+	}
+	var f data.AccountFilter
+	var err error
+	if f.FromBlock, err = parseOptionalBlock(filter.FromBlock); err != nil {
+		return f, err
+	}
+	if f.ToBlock, err = parseOptionalBlock(filter.ToBlock); err != nil {
+		return f, err
+	}
+	f.MinBalance = derefString(filter.MinBalance)
+	return f, nil
+}
+func parseOptionalChainID(chainID *string) (*uint64, error) {
+	if chainID == nil {
+		return nil, nil
+	}
+	id, err := parseChainID(*chainID)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+func parseBlockNumber(block string) (uint64, error) {
+	number, err := strconv.ParseUint(block, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block number %q: %v", block, err)
+	}
+	return number, nil
+}
+func parseOptionalBlock(block *string) (*uint64, error) {
+	if block == nil {
+		return nil, nil
+	}
+	number, err := parseBlockNumber(*block)
+	if err != nil {
+		return nil, err
+	}
+	return &number, nil
+}
+func parseBlockRange(startBlock, endBlock string) (uint64, uint64, error) {
+	start, err := parseBlockNumber(startBlock)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseBlockNumber(endBlock)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+func parseOptionalTime(value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q: %v", *value, err)
+	}
+	return &t, nil
+}
+func transactionConnection(bds *data.BlockchainDataStore, filter data.TransactionFilter, orderBy *model.TransactionOrder, first *int, after *string) (*model.TransactionConnection, error) {
+	page, err := bds.GetTransactions(filter, transactionOrder(orderBy), derefInt(first), derefString(after))
+	if err != nil {
+		return nil, err
+	}
+	return mapTransactionConnection(page), nil
+}
+func transactionOrder(orderBy *model.TransactionOrder) data.TransactionOrder {
+	if orderBy == nil {
+		return data.TransactionOrder{}
+	}
+	return data.TransactionOrder{Field: data.TransactionOrderField(orderBy.Field), Desc: isDesc(orderBy.Direction)}
+}
+func mapTransactionConnection(page *data.Page[*data.Transaction]) *model.TransactionConnection {
+	result := &model.TransactionConnection{
+		Edges:      make([]*model.TransactionEdge, 0, len(page.Edges)),
+		PageInfo:   mapPageInfo(page.HasNextPage, page.EndCursor()),
+		TotalCount: int(page.TotalCount),
+	}
+	for _, edge := range page.Edges {
+		result.Edges = append(result.Edges, &model.TransactionEdge{Cursor: edge.Cursor, Node: mapTransactionToModel(edge.Node)})
+	}
+	return result
+}
+func (r *Resolver) loadAccount(ctx context.Context, chainID, address string) (*model.Account, error) {
+	id, err := parseChainID(chainID)
+	if err != nil {
+		return nil, err
+	}
+	account, err := r.loaders(ctx).accounts.Load(ctx, chainKey{chainID: id, value: address})
+	if err != nil || account == nil {
+		return nil, err
+	}
+	return mapAccountToModel(account), nil
+}
@@ -0,0 +1,248 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+const (
+	// loaderWait is how long a loader collects keys before fetching them in one batch
+	loaderWait = 2 * time.Millisecond
+	// maxLoaderBatch is the largest number of keys fetched in one batch
+	maxLoaderBatch = data.MaxPageSize
+)
+
+type loadersKey struct{}
+
+// chainKey identifies a record by chain and hash or address
+type chainKey struct {
+	chainID uint64
+	value   string
+}
+
+// historyArgs are the arguments of the first page of the transactions of an account. Accounts
+// listed with the same arguments share a loader.
+type historyArgs struct {
+	first  int
+	filter string // JSON of the filter, which is not comparable
+	order  data.TransactionOrder
+}
+
+// Loaders batch the lookups of related records made while resolving a single response
+type Loaders struct {
+	bds                  *data.BlockchainDataStore
+	blocks               *loader[chainKey, *data.Block]
+	transactions         *loader[chainKey, []*data.Transaction]
+	internalTransactions *loader[chainKey, []*data.InternalTransaction]
+	accounts             *loader[chainKey, *data.Account]
+
+	mutex     sync.Mutex
+	histories map[historyArgs]*loader[chainKey, *data.Page[*data.Transaction]]
+}
+
+// NewLoaders creates the loaders of a response
+func NewLoaders(bds *data.BlockchainDataStore) *Loaders {
+	return &Loaders{
+		bds:       bds,
+		histories: make(map[historyArgs]*loader[chainKey, *data.Page[*data.Transaction]]),
+		blocks: newLoader(byChain(bds, func(bds *data.BlockchainDataStore, hashes []string) (map[string]*data.Block, error) {
+			blocks, err := bds.GetBlocksByHashes(hashes)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[string]*data.Block, len(blocks))
+			for _, block := range blocks {
+				result[block.Hash] = block
+			}
+			return result, nil
+		})),
+		transactions: newLoader(byChain(bds, func(bds *data.BlockchainDataStore, hashes []string) (map[string][]*data.Transaction, error) {
+			transactions, err := bds.GetTransactionsByBlockHashes(hashes)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[string][]*data.Transaction, len(hashes))
+			for _, tx := range transactions {
+				result[tx.BlockHash] = append(result[tx.BlockHash], tx)
+			}
+			return result, nil
+		})),
+		internalTransactions: newLoader(byChain(bds, func(bds *data.BlockchainDataStore, hashes []string) (map[string][]*data.InternalTransaction, error) {
+			internalTxs, err := bds.GetInternalTransactionsByTransactionHashes(hashes)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[string][]*data.InternalTransaction, len(hashes))
+			for _, internalTx := range internalTxs {
+				result[internalTx.TransactionHash] = append(result[internalTx.TransactionHash], internalTx)
+			}
+			return result, nil
+		})),
+		accounts: newLoader(byChain(bds, func(bds *data.BlockchainDataStore, addresses []string) (map[string]*data.Account, error) {
+			accounts, err := bds.GetAccountsByAddresses(addresses)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[string]*data.Account, len(accounts))
+			for _, account := range accounts {
+				result[account.Address] = account
+			}
+			return result, nil
+		})),
+	}
+}
+
+// history returns the loader of the first pages of account transactions with the given arguments
+func (l *Loaders) history(filter data.TransactionFilter, order data.TransactionOrder, first int) (*loader[chainKey, *data.Page[*data.Transaction]], error) {
+	encoded, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	args := historyArgs{first: first, filter: string(encoded), order: order}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if history, ok := l.histories[args]; ok {
+		return history, nil
+	}
+	history := newLoader(byChain(l.bds, func(bds *data.BlockchainDataStore, addresses []string) (map[string]*data.Page[*data.Transaction], error) {
+		return bds.GetFirstTransactionPages(addresses, filter, order, first)
+	}))
+	l.histories[args] = history
+	return history, nil
+}
+
+// LoaderExtension gives every response its own loaders, so results are only shared within a query
+// or a single event of a subscription
+type LoaderExtension struct {
+	BDS *data.BlockchainDataStore
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = LoaderExtension{}
+
+func (LoaderExtension) ExtensionName() string {
+	return "Loaders"
+}
+
+func (LoaderExtension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (e LoaderExtension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	return next(context.WithValue(ctx, loadersKey{}, NewLoaders(e.BDS)))
+}
+
+// loaders returns the loaders of the response, or new ones when the server does not use
+// LoaderExtension
+func (r *Resolver) loaders(ctx context.Context) *Loaders {
+	if loaders, ok := ctx.Value(loadersKey{}).(*Loaders); ok {
+		return loaders
+	}
+	return NewLoaders(r.BDS)
+}
+
+// byChain turns a fetch of values within a chain into a fetch of keys spanning chains
+func byChain[V any](bds *data.BlockchainDataStore, fetch func(*data.BlockchainDataStore, []string) (map[string]V, error)) func([]chainKey) (map[chainKey]V, error) {
+	return func(keys []chainKey) (map[chainKey]V, error) {
+		values := make(map[uint64][]string)
+		for _, key := range keys {
+			values[key.chainID] = append(values[key.chainID], key.value)
+		}
+
+		result := make(map[chainKey]V, len(keys))
+		for chainID, chainValues := range values {
+			fetched, err := fetch(bds.ForChain(chainID), chainValues)
+			if err != nil {
+				return nil, err
+			}
+			for value, v := range fetched {
+				result[chainKey{chainID: chainID, value: value}] = v
+			}
+		}
+		return result, nil
+	}
+}
+
+// loader collects the keys requested by concurrent resolvers and fetches them in one batch. Results
+// are cached for the lifetime of the loader.
+type loader[K comparable, V any] struct {
+	fetch   func([]K) (map[K]V, error)
+	mutex   sync.Mutex
+	cache   map[K]*loaderResult[V]
+	pending *loaderBatch[K, V]
+}
+
+type loaderResult[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type loaderBatch[K comparable, V any] struct {
+	keys       []K
+	results    []*loaderResult[V]
+	dispatched bool
+}
+
+func newLoader[K comparable, V any](fetch func([]K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, cache: make(map[K]*loaderResult[V])}
+}
+
+// Load returns the value of a key, the zero value if the key does not exist
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mutex.Lock()
+	result, ok := l.cache[key]
+	if !ok {
+		result = &loaderResult[V]{done: make(chan struct{})}
+		l.cache[key] = result
+		if l.pending == nil {
+			batch := &loaderBatch[K, V]{}
+			l.pending = batch
+			time.AfterFunc(loaderWait, func() { l.dispatch(batch) })
+		}
+		l.pending.keys = append(l.pending.keys, key)
+		l.pending.results = append(l.pending.results, result)
+		if len(l.pending.keys) >= maxLoaderBatch {
+			go l.dispatch(l.pending)
+			l.pending = nil
+		}
+	}
+	l.mutex.Unlock()
+
+	select {
+	case <-result.done:
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch fetches the keys of a batch once, when it is full or its wait is over
+func (l *loader[K, V]) dispatch(batch *loaderBatch[K, V]) {
+	l.mutex.Lock()
+	if l.pending == batch {
+		l.pending = nil
+	}
+	if batch.dispatched {
+		l.mutex.Unlock()
+		return
+	}
+	batch.dispatched = true
+	l.mutex.Unlock()
+
+	values, err := l.fetch(batch.keys)
+	for i, key := range batch.keys {
+		result := batch.results[i]
+		result.value, result.err = values[key], err
+		close(result.done)
+	}
+}
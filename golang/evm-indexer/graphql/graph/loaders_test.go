@@ -0,0 +1,219 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/ethereum/go-ethereum/common"
+	coreData "github.com/synkube/app/core/data"
+	"github.com/synkube/app/evm-indexer/config"
+	"github.com/synkube/app/evm-indexer/data"
+	"gorm.io/gorm"
+)
+
+const senders = 5
+
+var recipient = address(0x100)
+
+// address returns the checksummed address of a number
+func address(n int64) string {
+	return common.BigToAddress(big.NewInt(n)).Hex()
+}
+
+// hash returns the hash of a number
+func hash(n uint64) string {
+	return fmt.Sprintf("0x%064x", n)
+}
+
+// newTestServer serves a store on a fresh in-memory SQLite database like the graphql server, and
+// counts the queries made to the database
+func newTestServer(t *testing.T) (*data.BlockchainDataStore, *httptest.Server, *atomic.Int64) {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	ds := data.Initialize(&config.Config{DbConfig: coreData.DbConfig{
+		Type:   "sqlite",
+		SQLite: coreData.SQLiteConfig{File: fmt.Sprintf("file:%s?mode=memory&cache=shared", name)},
+	}})
+	sqlDB, err := ds.DB().DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	queries := &atomic.Int64{}
+	count := func(db *gorm.DB) {
+		// Subqueries are built by dry runs
+		if !db.DryRun {
+			queries.Add(1)
+		}
+	}
+	if err := ds.DB().Callback().Query().Before("gorm:query").Register("test:count_queries", count); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.DB().Callback().Row().Before("gorm:row").Register("test:count_rows", count); err != nil {
+		t.Fatal(err)
+	}
+
+	bds := data.NewBlockchainDataStore(ds, 0)
+	srv := handler.New(NewExecutableSchema(Config{Resolvers: &Resolver{BDS: bds}}))
+	srv.AddTransport(transport.POST{})
+	srv.Use(LoaderExtension{BDS: bds})
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+	return bds, server, queries
+}
+
+// saveTransfers saves block 1 with a transfer from each sender to recipient, each with two internal
+// transactions, and block 2 with a transfer from the first sender to the second
+func saveTransfers(t *testing.T, bds *data.BlockchainDataStore) {
+	t.Helper()
+	block := &data.IndexedBlock{Block: &data.Block{ID: hash(1), Hash: hash(1), Number: 1, NumberOfTxs: senders}}
+	for i := 0; i < senders; i++ {
+		to := recipient
+		txHash := hash(uint64(1000 + i))
+		block.Transactions = append(block.Transactions, &data.Transaction{
+			ID: txHash, BlockHash: hash(1), BlockNumber: 1, TransactionIndex: uint64(i),
+			FromAddress: address(int64(i + 1)), ToAddress: &to, Value: "1",
+		})
+		block.Accounts = append(block.Accounts, &data.Account{Address: address(int64(i + 1)), Balance: "1", BalanceBlock: 1})
+		for trace := uint64(0); trace < 2; trace++ {
+			block.InternalTransactions = append(block.InternalTransactions, &data.InternalTransaction{
+				ID: fmt.Sprintf("%s-%d", txHash, trace), BlockHash: hash(1), BlockNumber: 1, TransactionHash: txHash,
+				TraceIndex: trace, Type: "CALL", FromAddress: recipient, Value: "0", Depth: 1,
+			})
+		}
+	}
+	to := address(2)
+	next := &data.IndexedBlock{
+		Block: &data.Block{ID: hash(2), Hash: hash(2), Number: 2, NumberOfTxs: 1},
+		Transactions: []*data.Transaction{{
+			ID: hash(2000), BlockHash: hash(2), BlockNumber: 2, FromAddress: address(1), ToAddress: &to, Value: "1",
+		}},
+	}
+	for _, indexed := range []*data.IndexedBlock{block, next} {
+		if err := bds.ForChain(1).SaveBlock(indexed); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// query posts a GraphQL query and decodes its data into result
+func query(t *testing.T, server *httptest.Server, q string, result interface{}) {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"query": q})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Data   json.RawMessage
+		Errors []struct{ Message string }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Errors) > 0 {
+		t.Fatalf("query errors: %v", response.Errors)
+	}
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type connection struct {
+	TotalCount int
+	PageInfo   struct {
+		HasNextPage bool
+		EndCursor   *string
+	}
+	Edges []struct{ Node struct{ ID string } }
+}
+
+func TestNestedQueryIsBatched(t *testing.T) {
+	bds, server, queries := newTestServer(t)
+	saveTransfers(t, bds)
+	queries.Store(0)
+
+	var result struct {
+		Block struct {
+			Transactions []struct {
+				ID   string
+				From struct {
+					Address      string
+					Transactions connection
+				}
+				InternalTransactions []struct{ ID string }
+			}
+		}
+	}
+	query(t, server, fmt.Sprintf(`{ block(id: "%s", chainId: 1) { transactions {
+		id
+		from { address transactions(first: 1) { totalCount pageInfo { hasNextPage endCursor } edges { node { id } } } }
+		internalTransactions { id } } } }`, hash(1)), &result)
+
+	// The block, its transactions, their senders, the count and the first page of the transactions of
+	// the senders, and the internal transactions, whatever the number of transactions
+	if n := queries.Load(); n != 6 {
+		t.Errorf("%d queries for %d transactions, want 6", n, senders)
+	}
+
+	if len(result.Block.Transactions) != senders {
+		t.Fatalf("%d transactions, want %d", len(result.Block.Transactions), senders)
+	}
+	for i, tx := range result.Block.Transactions {
+		// The first two senders also sent or received the transaction of block 2
+		total := 1
+		if i < 2 {
+			total = 2
+		}
+		history := tx.From.Transactions
+		if history.TotalCount != total || history.PageInfo.HasNextPage != (total > 1) {
+			t.Errorf("sender %s has %d transactions with next page %t, want %d", tx.From.Address, history.TotalCount, history.PageInfo.HasNextPage, total)
+		}
+		if len(history.Edges) != 1 || history.Edges[0].Node.ID != tx.ID {
+			t.Errorf("first transactions of sender %s = %v, want %s", tx.From.Address, history.Edges, tx.ID)
+		}
+		if len(tx.InternalTransactions) != 2 || tx.InternalTransactions[0].ID != tx.ID+"-0" || tx.InternalTransactions[1].ID != tx.ID+"-1" {
+			t.Errorf("internal transactions of %s = %v, want its two calls in order", tx.ID, tx.InternalTransactions)
+		}
+	}
+}
+
+func TestAccountTransactionsNextPage(t *testing.T) {
+	bds, server, _ := newTestServer(t)
+	saveTransfers(t, bds)
+
+	var first struct {
+		Account struct{ Transactions connection }
+	}
+	q := `{ account(address: "%s", chainId: 1) { transactions(first: 1%s) {
+		totalCount pageInfo { hasNextPage endCursor } edges { node { id } } } } }`
+	query(t, server, fmt.Sprintf(q, strings.ToLower(address(2)), ""), &first)
+	page := first.Account.Transactions
+	if page.TotalCount != 2 || len(page.Edges) != 1 || page.Edges[0].Node.ID != hash(1001) || page.PageInfo.EndCursor == nil {
+		t.Fatalf("first page = %+v, want %s of 2 transactions", page, hash(1001))
+	}
+
+	var next struct {
+		Account struct{ Transactions connection }
+	}
+	query(t, server, fmt.Sprintf(q, address(2), fmt.Sprintf(`, after: "%s"`, *page.PageInfo.EndCursor)), &next)
+	page = next.Account.Transactions
+	if page.TotalCount != 2 || len(page.Edges) != 1 || page.Edges[0].Node.ID != hash(2000) || page.PageInfo.HasNextPage {
+		t.Errorf("next page = %+v, want the last transaction %s", page, hash(2000))
+	}
+}
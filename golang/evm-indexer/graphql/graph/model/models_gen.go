@@ -2,41 +2,401 @@
 
 package model
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
 type Account struct {
-	Address string `json:"address"`
-	Balance string `json:"balance"`
+	ChainID      string                 `json:"chainId"`
+	Address      string                 `json:"address"`
+	Balance      string                 `json:"balance"`
+	BalanceBlock string                 `json:"balanceBlock"`
+	Transactions *TransactionConnection `json:"transactions"`
+}
+
+type AccountBalance struct {
+	ChainID     string `json:"chainId"`
+	Address     string `json:"address"`
+	BlockNumber string `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	Balance     string `json:"balance"`
+}
+
+type AccountConnection struct {
+	Edges      []*AccountEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+}
+
+type AccountEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Account `json:"node"`
+}
+
+type AccountFilter struct {
+	FromBlock  *string `json:"fromBlock,omitempty"`
+	ToBlock    *string `json:"toBlock,omitempty"`
+	MinBalance *string `json:"minBalance,omitempty"`
+}
+
+type AccountOrder struct {
+	Field     AccountOrderField `json:"field"`
+	Direction *OrderDirection   `json:"direction,omitempty"`
 }
 
 type Block struct {
-	ID              string `json:"id"`
-	Hash            string `json:"hash"`
-	Number          string `json:"number"`
+	ChainID         string         `json:"chainId"`
+	ID              string         `json:"id"`
+	Hash            string         `json:"hash"`
+	Number          string         `json:"number"`
+	Timestamp       string         `json:"timestamp"`
+	NumberOfTxs     string         `json:"numberOfTxs"`
+	Miner           string         `json:"miner"`
+	ParentHash      string         `json:"parentHash"`
+	Difficulty      string         `json:"difficulty"`
+	TotalDifficulty string         `json:"totalDifficulty"`
+	Size            string         `json:"size"`
+	GasUsed         string         `json:"gasUsed"`
+	BaseFee         *string        `json:"baseFee,omitempty"`
+	GasLimit        string         `json:"gasLimit"`
+	Nonce           string         `json:"nonce"`
+	ExtraData       string         `json:"extraData"`
+	Transactions    []*Transaction `json:"transactions"`
+}
+
+type BlockConnection struct {
+	Edges      []*BlockEdge `json:"edges"`
+	PageInfo   *PageInfo    `json:"pageInfo"`
+	TotalCount int          `json:"totalCount"`
+}
+
+type BlockEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Block `json:"node"`
+}
+
+type BlockFilter struct {
+	FromBlock *string `json:"fromBlock,omitempty"`
+	ToBlock   *string `json:"toBlock,omitempty"`
+	FromTime  *string `json:"fromTime,omitempty"`
+	ToTime    *string `json:"toTime,omitempty"`
+	Miner     *string `json:"miner,omitempty"`
+}
+
+type BlockOrder struct {
+	Field     BlockOrderField `json:"field"`
+	Direction *OrderDirection `json:"direction,omitempty"`
+}
+
+type BlockRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Contract struct {
+	ChainID         string `json:"chainId"`
+	Address         string `json:"address"`
+	Cursor          string `json:"cursor"`
+	Creator         string `json:"creator"`
+	TransactionHash string `json:"transactionHash"`
+	BlockHash       string `json:"blockHash"`
+	BlockNumber     string `json:"blockNumber"`
+	BytecodeHash    string `json:"bytecodeHash"`
 	Timestamp       string `json:"timestamp"`
-	NumberOfTxs     string `json:"numberOfTxs"`
-	Miner           string `json:"miner"`
-	ParentHash      string `json:"parentHash"`
-	Difficulty      string `json:"difficulty"`
-	TotalDifficulty string `json:"totalDifficulty"`
-	Size            string `json:"size"`
-	GasUsed         string `json:"gasUsed"`
-	GasLimit        string `json:"gasLimit"`
-	Nonce           string `json:"nonce"`
-	ExtraData       string `json:"extraData"`
+}
+
+type DecodedEvent struct {
+	ChainID         string  `json:"chainId"`
+	ID              string  `json:"id"`
+	Cursor          string  `json:"cursor"`
+	BlockHash       string  `json:"blockHash"`
+	BlockNumber     string  `json:"blockNumber"`
+	TransactionHash string  `json:"transactionHash"`
+	LogIndex        *string `json:"logIndex,omitempty"`
+	ContractAddress string  `json:"contractAddress"`
+	ContractName    string  `json:"contractName"`
+	Kind            string  `json:"kind"`
+	Name            string  `json:"name"`
+	Signature       string  `json:"signature"`
+	Args            string  `json:"args"`
+	Timestamp       string  `json:"timestamp"`
+}
+
+type FailedBlock struct {
+	ChainID  string `json:"chainId"`
+	Number   string `json:"number"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+	FailedAt string `json:"failedAt"`
+}
+
+type IndexerState struct {
+	ChainID       string `json:"chainId"`
+	LowWaterMark  string `json:"lowWaterMark"`
+	HighWaterMark string `json:"highWaterMark"`
+	UpdatedAt     string `json:"updatedAt"`
+}
+
+type InternalTransaction struct {
+	ChainID         string  `json:"chainId"`
+	ID              string  `json:"id"`
+	TransactionHash string  `json:"transactionHash"`
+	BlockNumber     string  `json:"blockNumber"`
+	TraceIndex      string  `json:"traceIndex"`
+	Type            string  `json:"type"`
+	FromAddress     string  `json:"fromAddress"`
+	ToAddress       *string `json:"toAddress,omitempty"`
+	Value           string  `json:"value"`
+	Gas             string  `json:"gas"`
+	GasUsed         string  `json:"gasUsed"`
+	Depth           string  `json:"depth"`
+	Error           *string `json:"error,omitempty"`
+}
+
+type PageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor,omitempty"`
 }
 
 type Query struct {
 }
 
+type Subscription struct {
+}
+
+type TokenTransfer struct {
+	ChainID         string  `json:"chainId"`
+	ID              string  `json:"id"`
+	Cursor          string  `json:"cursor"`
+	BlockHash       string  `json:"blockHash"`
+	BlockNumber     string  `json:"blockNumber"`
+	TransactionHash string  `json:"transactionHash"`
+	LogIndex        string  `json:"logIndex"`
+	TokenAddress    string  `json:"tokenAddress"`
+	Standard        string  `json:"standard"`
+	Operator        *string `json:"operator,omitempty"`
+	FromAddress     string  `json:"fromAddress"`
+	ToAddress       string  `json:"toAddress"`
+	Amount          string  `json:"amount"`
+	TokenID         *string `json:"tokenId,omitempty"`
+	Timestamp       string  `json:"timestamp"`
+}
+
 type Transaction struct {
-	ID               string  `json:"id"`
-	BlockHash        string  `json:"blockHash"`
-	FromAddress      string  `json:"fromAddress"`
-	ToAddress        string `json:"toAddress,omitempty"`
-	Value            string  `json:"value"`
-	Gas              string  `json:"gas"`
-	GasPrice         string  `json:"gasPrice"`
-	InputData        string  `json:"inputData"`
-	Nonce            string  `json:"nonce"`
-	TransactionIndex string  `json:"transactionIndex"`
-	Timestamp        string  `json:"timestamp"`
+	ChainID              string                 `json:"chainId"`
+	ID                   string                 `json:"id"`
+	BlockHash            string                 `json:"blockHash"`
+	BlockNumber          string                 `json:"blockNumber"`
+	FromAddress          string                 `json:"fromAddress"`
+	ToAddress            *string                `json:"toAddress,omitempty"`
+	Value                string                 `json:"value"`
+	Gas                  string                 `json:"gas"`
+	GasPrice             string                 `json:"gasPrice"`
+	InputData            string                 `json:"inputData"`
+	Nonce                string                 `json:"nonce"`
+	TransactionIndex     string                 `json:"transactionIndex"`
+	Timestamp            string                 `json:"timestamp"`
+	Type                 int                    `json:"type"`
+	MaxFeePerGas         *string                `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *string                `json:"maxPriorityFeePerGas,omitempty"`
+	AccessList           *string                `json:"accessList,omitempty"`
+	BlobHashes           []string               `json:"blobHashes"`
+	CreatedContract      *string                `json:"createdContract,omitempty"`
+	InternalTransactions []*InternalTransaction `json:"internalTransactions"`
+	Block                *Block                 `json:"block,omitempty"`
+	From                 *Account               `json:"from,omitempty"`
+	To                   *Account               `json:"to,omitempty"`
+}
+
+type TransactionConnection struct {
+	Edges      []*TransactionEdge `json:"edges"`
+	PageInfo   *PageInfo          `json:"pageInfo"`
+	TotalCount int                `json:"totalCount"`
+}
+
+type TransactionEdge struct {
+	Cursor string       `json:"cursor"`
+	Node   *Transaction `json:"node"`
+}
+
+type TransactionFilter struct {
+	FromBlock   *string `json:"fromBlock,omitempty"`
+	ToBlock     *string `json:"toBlock,omitempty"`
+	FromTime    *string `json:"fromTime,omitempty"`
+	ToTime      *string `json:"toTime,omitempty"`
+	Address     *string `json:"address,omitempty"`
+	FromAddress *string `json:"fromAddress,omitempty"`
+	ToAddress   *string `json:"toAddress,omitempty"`
+	MinValue    *string `json:"minValue,omitempty"`
+}
+
+type TransactionOrder struct {
+	Field     TransactionOrderField `json:"field"`
+	Direction *OrderDirection       `json:"direction,omitempty"`
+}
+
+type AccountOrderField string
+
+const (
+	AccountOrderFieldAddress      AccountOrderField = "ADDRESS"
+	AccountOrderFieldBalanceBlock AccountOrderField = "BALANCE_BLOCK"
+)
+
+var AllAccountOrderField = []AccountOrderField{
+	AccountOrderFieldAddress,
+	AccountOrderFieldBalanceBlock,
+}
+
+func (e AccountOrderField) IsValid() bool {
+	switch e {
+	case AccountOrderFieldAddress, AccountOrderFieldBalanceBlock:
+		return true
+	}
+	return false
+}
+
+func (e AccountOrderField) String() string {
+	return string(e)
+}
+
+func (e *AccountOrderField) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AccountOrderField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AccountOrderField", str)
+	}
+	return nil
+}
+
+func (e AccountOrderField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type BlockOrderField string
+
+const (
+	BlockOrderFieldNumber  BlockOrderField = "NUMBER"
+	BlockOrderFieldGasUsed BlockOrderField = "GAS_USED"
+)
+
+var AllBlockOrderField = []BlockOrderField{
+	BlockOrderFieldNumber,
+	BlockOrderFieldGasUsed,
+}
+
+func (e BlockOrderField) IsValid() bool {
+	switch e {
+	case BlockOrderFieldNumber, BlockOrderFieldGasUsed:
+		return true
+	}
+	return false
+}
+
+func (e BlockOrderField) String() string {
+	return string(e)
+}
+
+func (e *BlockOrderField) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BlockOrderField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BlockOrderField", str)
+	}
+	return nil
+}
+
+func (e BlockOrderField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OrderDirection string
+
+const (
+	OrderDirectionAsc  OrderDirection = "ASC"
+	OrderDirectionDesc OrderDirection = "DESC"
+)
+
+var AllOrderDirection = []OrderDirection{
+	OrderDirectionAsc,
+	OrderDirectionDesc,
+}
+
+func (e OrderDirection) IsValid() bool {
+	switch e {
+	case OrderDirectionAsc, OrderDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e OrderDirection) String() string {
+	return string(e)
+}
+
+func (e *OrderDirection) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderDirection", str)
+	}
+	return nil
+}
+
+func (e OrderDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TransactionOrderField string
+
+const (
+	TransactionOrderFieldBlock TransactionOrderField = "BLOCK"
+	TransactionOrderFieldGas   TransactionOrderField = "GAS"
+)
+
+var AllTransactionOrderField = []TransactionOrderField{
+	TransactionOrderFieldBlock,
+	TransactionOrderFieldGas,
+}
+
+func (e TransactionOrderField) IsValid() bool {
+	switch e {
+	case TransactionOrderFieldBlock, TransactionOrderFieldGas:
+		return true
+	}
+	return false
+}
+
+func (e TransactionOrderField) String() string {
+	return string(e)
+}
+
+func (e *TransactionOrderField) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TransactionOrderField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TransactionOrderField", str)
+	}
+	return nil
+}
+
+func (e TransactionOrderField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
@@ -4,8 +4,34 @@ package graph
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
-import "github.com/synkube/app/evm-indexer/data"
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/synkube/app/evm-indexer/data"
+)
 
 type Resolver struct {
 	BDS *data.BlockchainDataStore
 }
+
+// store returns the data store limited to the requested chain, or spanning every chain without one
+func (r *Resolver) store(chainID *string) (*data.BlockchainDataStore, error) {
+	if chainID == nil {
+		return r.BDS, nil
+	}
+	id, err := parseChainID(*chainID)
+	if err != nil {
+		return nil, err
+	}
+	return r.BDS.ForChain(id), nil
+}
+
+// parseChainID parses a chain ID argument
+func parseChainID(chainID string) (uint64, error) {
+	id, err := strconv.ParseUint(chainID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid chain id %q: %v", chainID, err)
+	}
+	return id, nil
+}
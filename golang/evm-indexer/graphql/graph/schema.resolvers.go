@@ -7,28 +7,101 @@ package graph
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/synkube/app/evm-indexer/data"
 	"github.com/synkube/app/evm-indexer/graphql/graph/model"
 )
 
+// Transactions is the resolver for the transactions field.
+func (r *accountResolver) Transactions(ctx context.Context, obj *model.Account, first *int, after *string, filter *model.TransactionFilter, orderBy *model.TransactionOrder) (*model.TransactionConnection, error) {
+	bds, err := r.store(&obj.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := transactionFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if derefString(after) != "" {
+		f.Address = obj.Address
+		return transactionConnection(bds, f, orderBy, first, after)
+	}
+
+	// The first pages of the accounts of a response are fetched together
+	chainID, err := parseChainID(obj.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := r.loaders(ctx).history(f, transactionOrder(orderBy), derefInt(first))
+	if err != nil {
+		return nil, err
+	}
+	page, err := history.Load(ctx, chainKey{chainID: chainID, value: obj.Address})
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		page = &data.Page[*data.Transaction]{}
+	}
+	return mapTransactionConnection(page), nil
+}
+
+// Transactions is the resolver for the transactions field.
+func (r *blockResolver) Transactions(ctx context.Context, obj *model.Block) ([]*model.Transaction, error) {
+	chainID, err := parseChainID(obj.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := r.loaders(ctx).transactions.Load(ctx, chainKey{chainID: chainID, value: obj.Hash})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		result = append(result, mapTransactionToModel(tx))
+	}
+	return result, nil
+}
+
 // Blocks is the resolver for the blocks field.
-func (r *queryResolver) Blocks(ctx context.Context) ([]*model.Block, error) {
-	blocks, err := r.BDS.GetAllBlocks()
+func (r *queryResolver) Blocks(ctx context.Context, first *int, after *string, filter *model.BlockFilter, orderBy *model.BlockOrder, chainID *string) (*model.BlockConnection, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := blockFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	order := data.BlockOrder{}
+	if orderBy != nil {
+		order = data.BlockOrder{Field: data.BlockOrderField(orderBy.Field), Desc: isDesc(orderBy.Direction)}
+	}
+	page, err := bds.GetBlocks(f, order, derefInt(first), derefString(after))
 	if err != nil {
 		return nil, err
 	}
 
-	var result []*model.Block
-	for _, block := range blocks {
-		result = append(result, mapBlockToModel(block))
+	result := &model.BlockConnection{
+		Edges:      make([]*model.BlockEdge, 0, len(page.Edges)),
+		PageInfo:   mapPageInfo(page.HasNextPage, page.EndCursor()),
+		TotalCount: int(page.TotalCount),
+	}
+	for _, edge := range page.Edges {
+		result.Edges = append(result.Edges, &model.BlockEdge{Cursor: edge.Cursor, Node: mapBlockToModel(edge.Node)})
 	}
 	return result, nil
 }
 
 // Block is the resolver for the block field.
-func (r *queryResolver) Block(ctx context.Context, id string) (*model.Block, error) {
-	block, err := r.BDS.GetBlockByID(id)
+func (r *queryResolver) Block(ctx context.Context, id string, chainID *string) (*model.Block, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	block, err := bds.GetBlockByID(id)
 	if err != nil {
 		return nil, err
 	}
@@ -36,22 +109,25 @@ func (r *queryResolver) Block(ctx context.Context, id string) (*model.Block, err
 }
 
 // Transactions is the resolver for the transactions field.
-func (r *queryResolver) Transactions(ctx context.Context) ([]*model.Transaction, error) {
-	transactions, err := r.BDS.GetAllTransactions()
+func (r *queryResolver) Transactions(ctx context.Context, first *int, after *string, filter *model.TransactionFilter, orderBy *model.TransactionOrder, chainID *string) (*model.TransactionConnection, error) {
+	bds, err := r.store(chainID)
 	if err != nil {
 		return nil, err
 	}
-
-	var result []*model.Transaction
-	for _, tx := range transactions {
-		result = append(result, mapTransactionToModel(tx))
+	f, err := transactionFilter(filter)
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	return transactionConnection(bds, f, orderBy, first, after)
 }
 
 // Transaction is the resolver for the transaction field.
-func (r *queryResolver) Transaction(ctx context.Context, id string) (*model.Transaction, error) {
-	tx, err := r.BDS.GetTransactionByID(id)
+func (r *queryResolver) Transaction(ctx context.Context, id string, chainID *string) (*model.Transaction, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := bds.GetTransactionByID(id)
 	if err != nil {
 		return nil, err
 	}
@@ -59,92 +135,325 @@ func (r *queryResolver) Transaction(ctx context.Context, id string) (*model.Tran
 }
 
 // Accounts is the resolver for the accounts field.
-func (r *queryResolver) Accounts(ctx context.Context) ([]*model.Account, error) {
-	accounts, err := r.BDS.GetAllAccounts()
+func (r *queryResolver) Accounts(ctx context.Context, first *int, after *string, filter *model.AccountFilter, orderBy *model.AccountOrder, chainID *string) (*model.AccountConnection, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := accountFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	order := data.AccountOrder{}
+	if orderBy != nil {
+		order = data.AccountOrder{Field: data.AccountOrderField(orderBy.Field), Desc: isDesc(orderBy.Direction)}
+	}
+	page, err := bds.GetAccounts(f, order, derefInt(first), derefString(after))
 	if err != nil {
 		return nil, err
 	}
 
-	var result []*model.Account
-	for _, account := range accounts {
-		result = append(result, mapAccountToModel(account))
+	result := &model.AccountConnection{
+		Edges:      make([]*model.AccountEdge, 0, len(page.Edges)),
+		PageInfo:   mapPageInfo(page.HasNextPage, page.EndCursor()),
+		TotalCount: int(page.TotalCount),
+	}
+	for _, edge := range page.Edges {
+		result.Edges = append(result.Edges, &model.AccountEdge{Cursor: edge.Cursor, Node: mapAccountToModel(edge.Node)})
 	}
 	return result, nil
 }
 
 // Account is the resolver for the account field.
-func (r *queryResolver) Account(ctx context.Context, address string) (*model.Account, error) {
-	account, err := r.BDS.GetAccountByAddress(address)
+func (r *queryResolver) Account(ctx context.Context, address string, chainID *string) (*model.Account, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	account, err := bds.GetAccountByAddress(address)
 	if err != nil {
 		return nil, err
 	}
 	return mapAccountToModel(account), nil
 }
 
+// AccountBalanceAt is the resolver for the accountBalanceAt field.
+func (r *queryResolver) AccountBalanceAt(ctx context.Context, address string, block string, chainID *string) (*model.AccountBalance, error) {
+	blockNumber, err := strconv.ParseUint(block, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block %q: %v", block, err)
+	}
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := bds.GetAccountBalanceAt(address, blockNumber)
+	if err != nil || balance == nil {
+		return nil, err
+	}
+	return mapAccountBalanceToModel(balance), nil
+}
+
+// Contract is the resolver for the contract field.
+func (r *queryResolver) Contract(ctx context.Context, address string, chainID *string) (*model.Contract, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	contract, err := bds.GetContractByAddress(address)
+	if err != nil || contract == nil {
+		return nil, err
+	}
+	return mapContractToModel(contract), nil
+}
+
+// Contracts is the resolver for the contracts field.
+func (r *queryResolver) Contracts(ctx context.Context, creator string, first *int, after *string, chainID *string) ([]*model.Contract, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	contracts, err := bds.GetContractsByCreator(creator, derefInt(first), derefString(after))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Contract, 0, len(contracts))
+	for _, contract := range contracts {
+		result = append(result, mapContractToModel(contract))
+	}
+	return result, nil
+}
+
 // BlocksInRange is the resolver for the blocksInRange field.
-func (r *queryResolver) BlocksInRange(ctx context.Context, startBlock string, endBlock string) ([]*model.Block, error) {
-	panic(fmt.Errorf("not implemented: BlocksInRange - blocksInRange"))
+func (r *queryResolver) BlocksInRange(ctx context.Context, startBlock string, endBlock string, chainID *string) ([]*model.Block, error) {
+	start, end, err := parseBlockRange(startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := bds.GetBlocksInRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Block, 0, len(blocks))
+	for _, block := range blocks {
+		result = append(result, mapBlockToModel(block))
+	}
+	return result, nil
 }
 
 // MissingBlocks is the resolver for the missingBlocks field.
-func (r *queryResolver) MissingBlocks(ctx context.Context, startBlock string, endBlock string) ([]string, error) {
-	panic(fmt.Errorf("not implemented: MissingBlocks - missingBlocks"))
+func (r *queryResolver) MissingBlocks(ctx context.Context, startBlock string, endBlock string, chainID string) ([]*model.BlockRange, error) {
+	start, end, err := parseBlockRange(startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	bds, err := r.store(&chainID)
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := bds.GetMissingBlockRanges(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.BlockRange, 0, len(ranges))
+	for _, blockRange := range ranges {
+		result = append(result, &model.BlockRange{From: fmt.Sprint(blockRange.From), To: fmt.Sprint(blockRange.To)})
+	}
+	return result, nil
 }
 
-// Query returns QueryResolver implementation.
-func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+// TokenTransfers is the resolver for the tokenTransfers field.
+func (r *queryResolver) TokenTransfers(ctx context.Context, address *string, token *string, first *int, after *string, chainID *string) ([]*model.TokenTransfer, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := bds.GetTokenTransfers(derefString(address), derefString(token), derefInt(first), derefString(after))
+	if err != nil {
+		return nil, err
+	}
 
-type queryResolver struct{ *Resolver }
+	result := make([]*model.TokenTransfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		result = append(result, mapTokenTransferToModel(transfer))
+	}
+	return result, nil
+}
 
-// !!! WARNING !!!
-// The code below was going to be deleted when updating resolvers. It has been copied here so you have
-// one last chance to move it out of harms way if you want. There are two reasons this happens:
-//   - When renaming or deleting a resolver the old code will be put in here. You can safely delete
-//     it when you're done.
-//   - You have helper methods in this file. Move them out to keep these resolver files clean.
-func mapBlockToModel(block *data.Block) *model.Block {
-	return &model.Block{
-		ID:              block.ID,
-		Hash:            block.Hash,
-		Number:          fmt.Sprint(block.Number),
-		Timestamp:       block.Timestamp.String(),
-		NumberOfTxs:     fmt.Sprint(block.NumberOfTxs),
-		Miner:           block.Miner,
-		ParentHash:      block.ParentHash,
-		Difficulty:      block.Difficulty,
-		TotalDifficulty: block.TotalDifficulty,
-		Size:            fmt.Sprint(block.Size),
-		GasUsed:         fmt.Sprint(block.GasUsed),
-		GasLimit:        fmt.Sprint(block.GasLimit),
-		Nonce:           block.Nonce,
-		ExtraData:       block.ExtraData,
-	}
-}
-func mapTransactionToModel(tx *data.Transaction) *model.Transaction {
-	return &model.Transaction{
-		ID:               tx.ID,
-		BlockHash:        tx.BlockHash,
-		FromAddress:      tx.FromAddress,
-		ToAddress:        fmt.Sprint(tx.ToAddress),
-		Value:            tx.Value,
-		Gas:              fmt.Sprint(tx.Gas),
-		GasPrice:         tx.GasPrice,
-		InputData:        tx.InputData,
-		Nonce:            fmt.Sprint(tx.Nonce),
-		TransactionIndex: fmt.Sprint(tx.TransactionIndex),
-		Timestamp:        tx.Timestamp.String(),
-	}
-}
-func mapAccountToModel(account *data.Account) *model.Account {
-	return &model.Account{
-		Address: account.Address,
-		Balance: account.Balance,
-	}
-}
-func mapTransactionsToModel(txs []*data.Transaction) []*model.Transaction {
-	var result []*model.Transaction
-	for _, tx := range txs {
-		result = append(result, mapTransactionToModel(tx))
+// DecodedEvents is the resolver for the decodedEvents field.
+func (r *queryResolver) DecodedEvents(ctx context.Context, contract string, name *string, first *int, after *string, chainID *string) ([]*model.DecodedEvent, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := bds.GetDecodedEvents(contract, derefString(name), derefInt(first), derefString(after))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.DecodedEvent, 0, len(events))
+	for _, event := range events {
+		result = append(result, mapDecodedEventToModel(event))
+	}
+	return result, nil
+}
+
+// FailedBlocks is the resolver for the failedBlocks field.
+func (r *queryResolver) FailedBlocks(ctx context.Context, chainID *string) ([]*model.FailedBlock, error) {
+	bds, err := r.store(chainID)
+	if err != nil {
+		return nil, err
+	}
+	failedBlocks, err := bds.GetFailedBlocks()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.FailedBlock, 0, len(failedBlocks))
+	for _, failed := range failedBlocks {
+		result = append(result, mapFailedBlockToModel(failed))
+	}
+	return result, nil
+}
+
+// IndexerState is the resolver for the indexerState field.
+func (r *queryResolver) IndexerState(ctx context.Context, chainID string) (*model.IndexerState, error) {
+	id, err := parseChainID(chainID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := r.BDS.GetIndexerState(id)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	return mapIndexerStateToModel(state), nil
+}
+
+// NewBlocks is the resolver for the newBlocks field.
+func (r *subscriptionResolver) NewBlocks(ctx context.Context, chainID *string) (<-chan *model.Block, error) {
+	chain, err := parseOptionalChainID(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make(chan *model.Block)
+	go func() {
+		defer close(blocks)
+		for event := range r.BDS.Events().Subscribe(ctx) {
+			if chain != nil && event.Block.ChainID != *chain {
+				continue
+			}
+			select {
+			case blocks <- mapBlockToModel(event.Block):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+// NewTransactions is the resolver for the newTransactions field.
+func (r *subscriptionResolver) NewTransactions(ctx context.Context, filter *model.TransactionFilter, chainID *string) (<-chan *model.Transaction, error) {
+	chain, err := parseOptionalChainID(chainID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := transactionFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make(chan *model.Transaction)
+	go func() {
+		defer close(transactions)
+		for event := range r.BDS.Events().Subscribe(ctx) {
+			if chain != nil && event.Block.ChainID != *chain {
+				continue
+			}
+			for _, tx := range event.Transactions {
+				if !f.Matches(tx) {
+					continue
+				}
+				select {
+				case transactions <- mapTransactionToModel(tx):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return transactions, nil
+}
+
+// InternalTransactions is the resolver for the internalTransactions field.
+func (r *transactionResolver) InternalTransactions(ctx context.Context, obj *model.Transaction) ([]*model.InternalTransaction, error) {
+	chainID, err := parseChainID(obj.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	internalTxs, err := r.loaders(ctx).internalTransactions.Load(ctx, chainKey{chainID: chainID, value: obj.ID})
+	if err != nil {
+		return nil, err
 	}
-	return result
+
+	result := make([]*model.InternalTransaction, 0, len(internalTxs))
+	for _, internalTx := range internalTxs {
+		result = append(result, mapInternalTransactionToModel(internalTx))
+	}
+	return result, nil
+}
+
+// Block is the resolver for the block field.
+func (r *transactionResolver) Block(ctx context.Context, obj *model.Transaction) (*model.Block, error) {
+	chainID, err := parseChainID(obj.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	block, err := r.loaders(ctx).blocks.Load(ctx, chainKey{chainID: chainID, value: obj.BlockHash})
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return mapBlockToModel(block), nil
+}
+
+// From is the resolver for the from field.
+func (r *transactionResolver) From(ctx context.Context, obj *model.Transaction) (*model.Account, error) {
+	return r.loadAccount(ctx, obj.ChainID, obj.FromAddress)
+}
+
+// To is the resolver for the to field.
+func (r *transactionResolver) To(ctx context.Context, obj *model.Transaction) (*model.Account, error) {
+	if obj.ToAddress == nil {
+		return nil, nil
+	}
+	return r.loadAccount(ctx, obj.ChainID, *obj.ToAddress)
 }
+
+// Account returns AccountResolver implementation.
+func (r *Resolver) Account() AccountResolver { return &accountResolver{r} }
+
+// Block returns BlockResolver implementation.
+func (r *Resolver) Block() BlockResolver { return &blockResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+// Transaction returns TransactionResolver implementation.
+func (r *Resolver) Transaction() TransactionResolver { return &transactionResolver{r} }
+
+type accountResolver struct{ *Resolver }
+type blockResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type transactionResolver struct{ *Resolver }
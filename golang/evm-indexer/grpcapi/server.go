@@ -0,0 +1,208 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/synkube/app/evm-indexer/data"
+	"github.com/synkube/app/evm-indexer/grpcapi/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// Server implements the IndexerService on a BlockchainDataStore
+type Server struct {
+	pb.UnimplementedIndexerServiceServer
+	bds *data.BlockchainDataStore
+}
+
+// NewServer creates a Server reading from bds
+func NewServer(bds *data.BlockchainDataStore) *Server {
+	return &Server{bds: bds}
+}
+
+// NewGRPCServer creates a gRPC server serving the IndexerService, with reflection enabled
+func NewGRPCServer(bds *data.BlockchainDataStore) *grpc.Server {
+	s := grpc.NewServer()
+	pb.RegisterIndexerServiceServer(s, NewServer(bds))
+	reflection.Register(s)
+	return s
+}
+
+// store returns the data store limited to the requested chain, or spanning every chain without one
+func (s *Server) store(chainID uint64) *data.BlockchainDataStore {
+	if chainID == 0 {
+		return s.bds
+	}
+	return s.bds.ForChain(chainID)
+}
+
+func (s *Server) GetBlock(ctx context.Context, req *pb.GetBlockRequest) (*pb.Block, error) {
+	bds := s.store(req.ChainId)
+	var block *data.Block
+	var err error
+	switch selector := req.Block.(type) {
+	case *pb.GetBlockRequest_Hash:
+		block, err = bds.GetBlockByID(selector.Hash)
+	case *pb.GetBlockRequest_Number:
+		block, err = bds.GetBlockByNumber(selector.Number)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "hash or number is required")
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if block == nil {
+		return nil, status.Error(codes.NotFound, "block not found")
+	}
+	return toBlock(block), nil
+}
+
+func (s *Server) ListBlocks(ctx context.Context, req *pb.ListBlocksRequest) (*pb.ListBlocksResponse, error) {
+	filter := data.BlockFilter{FromBlock: req.FromBlock, ToBlock: req.ToBlock}
+	order := data.BlockOrder{Field: data.BlockOrderNumber, Desc: req.Descending}
+	page, err := s.store(req.ChainId).GetBlocks(filter, order, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &pb.ListBlocksResponse{Blocks: make([]*pb.Block, 0, len(page.Edges)), TotalCount: page.TotalCount}
+	for _, edge := range page.Edges {
+		resp.Blocks = append(resp.Blocks, toBlock(edge.Node))
+	}
+	if page.HasNextPage {
+		resp.NextPageToken = page.EndCursor()
+	}
+	return resp, nil
+}
+
+func (s *Server) GetTransaction(ctx context.Context, req *pb.GetTransactionRequest) (*pb.Transaction, error) {
+	if req.Hash == "" {
+		return nil, status.Error(codes.InvalidArgument, "hash is required")
+	}
+	tx, err := s.store(req.ChainId).GetTransactionByID(req.Hash)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTransaction(tx), nil
+}
+
+func (s *Server) ListTransactionsByAddress(ctx context.Context, req *pb.ListTransactionsByAddressRequest) (*pb.ListTransactionsResponse, error) {
+	if req.Address == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+	filter := data.TransactionFilter{Address: req.Address, FromBlock: req.FromBlock, ToBlock: req.ToBlock}
+	order := data.TransactionOrder{Field: data.TransactionOrderBlock, Desc: req.Descending}
+	page, err := s.store(req.ChainId).GetTransactions(filter, order, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &pb.ListTransactionsResponse{Transactions: make([]*pb.Transaction, 0, len(page.Edges)), TotalCount: page.TotalCount}
+	for _, edge := range page.Edges {
+		resp.Transactions = append(resp.Transactions, toTransaction(edge.Node))
+	}
+	if page.HasNextPage {
+		resp.NextPageToken = page.EndCursor()
+	}
+	return resp, nil
+}
+
+func (s *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.Account, error) {
+	if req.Address == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+	account, err := s.store(req.ChainId).GetAccountByAddress(req.Address)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Account{
+		ChainId:      account.ChainID,
+		Address:      account.Address,
+		Balance:      account.Balance,
+		BalanceBlock: account.BalanceBlock,
+	}, nil
+}
+
+func (s *Server) StreamBlocks(req *pb.StreamBlocksRequest, stream pb.IndexerService_StreamBlocksServer) error {
+	ctx := stream.Context()
+	for event := range s.bds.Events().Subscribe(ctx) {
+		if req.ChainId != 0 && event.Block.ChainID != req.ChainId {
+			continue
+		}
+		if err := stream.Send(toBlock(event.Block)); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// toStatus converts a data store error into a gRPC status. Internal errors are only logged, as
+// their text may reveal details of the database.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, data.ErrInvalidCursor), errors.Is(err, data.ErrInvalidAddress):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, data.ErrChainRequired):
+		return status.Error(codes.InvalidArgument, "chain_id is required when several chains are indexed")
+	default:
+		log.Printf("Error serving gRPC request: %v", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// stringValue returns the value of a nullable column, empty for NULL as proto3 strings have no null
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func toBlock(block *data.Block) *pb.Block {
+	return &pb.Block{
+		ChainId:         block.ChainID,
+		Hash:            block.Hash,
+		Number:          block.Number,
+		Timestamp:       timestamppb.New(block.Timestamp),
+		NumberOfTxs:     block.NumberOfTxs,
+		Miner:           block.Miner,
+		ParentHash:      block.ParentHash,
+		Difficulty:      block.Difficulty,
+		TotalDifficulty: block.TotalDifficulty,
+		Size:            block.Size,
+		GasUsed:         block.GasUsed,
+		GasLimit:        block.GasLimit,
+		Nonce:           block.Nonce,
+		ExtraData:       block.ExtraData,
+	}
+}
+
+func toTransaction(tx *data.Transaction) *pb.Transaction {
+	return &pb.Transaction{
+		ChainId:              tx.ChainID,
+		Hash:                 tx.ID,
+		BlockHash:            tx.BlockHash,
+		BlockNumber:          tx.BlockNumber,
+		TransactionIndex:     tx.TransactionIndex,
+		FromAddress:          tx.FromAddress,
+		ToAddress:            stringValue(tx.ToAddress),
+		Value:                tx.Value,
+		Gas:                  tx.Gas,
+		GasPrice:             tx.GasPrice,
+		InputData:            tx.InputData,
+		Nonce:                tx.Nonce,
+		Timestamp:            timestamppb.New(tx.Timestamp),
+		Type:                 uint32(tx.Type),
+		MaxFeePerGas:         tx.MaxFeePerGas,
+		MaxPriorityFeePerGas: tx.MaxPriorityFeePerGas,
+		CreatedContract:      tx.CreatedContract,
+	}
+}
@@ -0,0 +1,295 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	coreData "github.com/synkube/app/core/data"
+	"github.com/synkube/app/evm-indexer/config"
+	"github.com/synkube/app/evm-indexer/data"
+	"github.com/synkube/app/evm-indexer/grpcapi/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	sender    = "0x0000000000000000000000000000000000000001"
+	recipient = "0x0000000000000000000000000000000000000002"
+)
+
+// newTestStore creates a store on a fresh in-memory SQLite database
+func newTestStore(t *testing.T) *data.BlockchainDataStore {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	ds := data.Initialize(&config.Config{DbConfig: coreData.DbConfig{
+		Type:   "sqlite",
+		SQLite: coreData.SQLiteConfig{File: fmt.Sprintf("file:%s?mode=memory&cache=shared", name)},
+	}})
+	sqlDB, err := ds.DB().DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return data.NewBlockchainDataStore(ds, 0)
+}
+
+// blockHash returns the hash of the test block with the given number
+func blockHash(number uint64) string {
+	return fmt.Sprintf("0x%064x", number)
+}
+
+// saveBlocks stores blocks of chain 1 with one transfer from sender to recipient each
+func saveBlocks(t *testing.T, bds *data.BlockchainDataStore, numbers ...uint64) {
+	t.Helper()
+	for _, number := range numbers {
+		hash := blockHash(number)
+		to := recipient
+		err := bds.ForChain(1).SaveBlock(&data.IndexedBlock{
+			Block: &data.Block{ID: hash, Hash: hash, Number: number, NumberOfTxs: 1},
+			Transactions: []*data.Transaction{{
+				ID:          fmt.Sprintf("0x%064x", 1000+number),
+				BlockHash:   hash,
+				BlockNumber: number,
+				FromAddress: sender,
+				ToAddress:   &to,
+				Value:       "1",
+			}},
+			Accounts: []*data.Account{{Address: sender, Balance: fmt.Sprint(100 - number), BalanceBlock: number}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newTestClient serves bds with NewGRPCServer on an in-memory listener and returns a client of it
+func newTestClient(t *testing.T, bds *data.BlockchainDataStore) pb.IndexerServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewGRPCServer(bds)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewIndexerServiceClient(conn)
+}
+
+func TestGetBlock(t *testing.T) {
+	bds := newTestStore(t)
+	saveBlocks(t, bds, 1, 2, 3)
+	client := newTestClient(t, bds)
+	ctx := context.Background()
+
+	block, err := client.GetBlock(ctx, &pb.GetBlockRequest{Block: &pb.GetBlockRequest_Hash{Hash: strings.ToUpper(blockHash(2))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Number != 2 || block.ChainId != 1 {
+		t.Errorf("block by hash = %d of chain %d, want 2 of chain 1", block.Number, block.ChainId)
+	}
+
+	block, err = client.GetBlock(ctx, &pb.GetBlockRequest{ChainId: 1, Block: &pb.GetBlockRequest_Number{Number: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Hash != blockHash(3) {
+		t.Errorf("block by number = %s, want %s", block.Hash, blockHash(3))
+	}
+}
+
+func TestGetBlockNotFound(t *testing.T) {
+	bds := newTestStore(t)
+	saveBlocks(t, bds, 1)
+	client := newTestClient(t, bds)
+	ctx := context.Background()
+
+	for _, req := range []*pb.GetBlockRequest{
+		{Block: &pb.GetBlockRequest_Hash{Hash: blockHash(9)}},
+		{ChainId: 1, Block: &pb.GetBlockRequest_Number{Number: 9}},
+		{ChainId: 2, Block: &pb.GetBlockRequest_Number{Number: 1}},
+	} {
+		if _, err := client.GetBlock(ctx, req); status.Code(err) != codes.NotFound {
+			t.Errorf("GetBlock(%v) error = %v, want NotFound", req, err)
+		}
+	}
+	if _, err := client.GetBlock(ctx, &pb.GetBlockRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetBlock without hash or number error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGetBlockByNumberRequiresChain(t *testing.T) {
+	bds := newTestStore(t)
+	saveBlocks(t, bds, 1)
+	hash := blockHash(1)
+	if err := bds.ForChain(2).SaveBlock(&data.IndexedBlock{Block: &data.Block{ID: hash, Hash: hash, Number: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, bds)
+	ctx := context.Background()
+
+	_, err := client.GetBlock(ctx, &pb.GetBlockRequest{Block: &pb.GetBlockRequest_Number{Number: 1}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetBlock by number without chain error = %v, want InvalidArgument", err)
+	}
+	block, err := client.GetBlock(ctx, &pb.GetBlockRequest{ChainId: 2, Block: &pb.GetBlockRequest_Number{Number: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.ChainId != 2 {
+		t.Errorf("block of chain %d, want 2", block.ChainId)
+	}
+}
+
+func TestListBlocksPages(t *testing.T) {
+	bds := newTestStore(t)
+	saveBlocks(t, bds, 1, 2, 3, 4, 5)
+	client := newTestClient(t, bds)
+
+	var numbers []uint64
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatal("more than 3 pages of 2 blocks for 5 blocks")
+		}
+		resp, err := client.ListBlocks(context.Background(), &pb.ListBlocksRequest{ChainId: 1, PageSize: 2, PageToken: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.TotalCount != 5 {
+			t.Errorf("total count = %d, want 5", resp.TotalCount)
+		}
+		for _, block := range resp.Blocks {
+			numbers = append(numbers, block.Number)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		token = resp.NextPageToken
+	}
+	if fmt.Sprint(numbers) != "[1 2 3 4 5]" {
+		t.Errorf("listed blocks %v, want [1 2 3 4 5]", numbers)
+	}
+
+	_, err := client.ListBlocks(context.Background(), &pb.ListBlocksRequest{PageToken: "invalid"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListBlocks with an invalid page token error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestListTransactionsByAddressPages(t *testing.T) {
+	bds := newTestStore(t)
+	saveBlocks(t, bds, 1, 2, 3)
+	client := newTestClient(t, bds)
+
+	var blocks []uint64
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatal("more than 3 pages of 1 transaction for 3 transactions")
+		}
+		resp, err := client.ListTransactionsByAddress(context.Background(), &pb.ListTransactionsByAddressRequest{
+			ChainId:    1,
+			Address:    strings.ToLower(recipient),
+			PageSize:   1,
+			PageToken:  token,
+			Descending: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tx := range resp.Transactions {
+			if tx.ToAddress != recipient {
+				t.Errorf("transaction %s to %s, want %s", tx.Hash, tx.ToAddress, recipient)
+			}
+			blocks = append(blocks, tx.BlockNumber)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		token = resp.NextPageToken
+	}
+	if fmt.Sprint(blocks) != "[3 2 1]" {
+		t.Errorf("listed transactions of blocks %v, want [3 2 1]", blocks)
+	}
+
+	_, err := client.ListTransactionsByAddress(context.Background(), &pb.ListTransactionsByAddressRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListTransactionsByAddress without address error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	bds := newTestStore(t)
+	saveBlocks(t, bds, 1, 2)
+	client := newTestClient(t, bds)
+	ctx := context.Background()
+
+	for _, address := range []string{sender, strings.ToLower(sender), strings.ToUpper(sender[2:])} {
+		account, err := client.GetAccount(ctx, &pb.GetAccountRequest{ChainId: 1, Address: address})
+		if err != nil {
+			t.Fatalf("GetAccount(%s): %v", address, err)
+		}
+		if account.Balance != "98" || account.BalanceBlock != 2 {
+			t.Errorf("balance of %s %s at block %d, want 98 at block 2", address, account.Balance, account.BalanceBlock)
+		}
+	}
+
+	_, err := client.GetAccount(ctx, &pb.GetAccountRequest{ChainId: 1, Address: recipient})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetAccount of an unknown account error = %v, want NotFound", err)
+	}
+	_, err = client.GetAccount(ctx, &pb.GetAccountRequest{ChainId: 1, Address: "0x1234"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetAccount of a malformed address error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestStreamBlocks(t *testing.T) {
+	bds := newTestStore(t)
+	client := newTestClient(t, bds)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamBlocks(ctx, &pb.StreamBlocksRequest{ChainId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server subscribes asynchronously: publish until the stream delivers a block
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			bds.Events().Publish(&data.BlockEvent{Block: &data.Block{ChainID: 2, Hash: blockHash(1), Number: 1}})
+			bds.Events().Publish(&data.BlockEvent{Block: &data.Block{ChainID: 1, Hash: blockHash(2), Number: 2}})
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	block, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.ChainId != 1 || block.Number != 2 {
+		t.Errorf("streamed block %d of chain %d, want 2 of chain 1", block.Number, block.ChainId)
+	}
+}
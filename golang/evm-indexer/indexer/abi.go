@@ -0,0 +1,221 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	goEthCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/synkube/app/evm-indexer/config"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+// ABIRegistry holds the ABIs of the configured contracts to decode their logs and calldata
+type ABIRegistry struct {
+	contracts map[goEthCommon.Address]*registeredContract
+}
+
+type registeredContract struct {
+	name string
+	abi  abi.ABI
+}
+
+// NewABIRegistry loads the ABI JSON file of every configured contract
+func NewABIRegistry(contracts []config.Contract) (*ABIRegistry, error) {
+	registry := &ABIRegistry{contracts: make(map[goEthCommon.Address]*registeredContract, len(contracts))}
+	for _, contract := range contracts {
+		if !goEthCommon.IsHexAddress(contract.Address) {
+			return nil, fmt.Errorf("invalid address %q for contract %s", contract.Address, contract.Name)
+		}
+
+		file, err := os.Open(contract.ABI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ABI of contract %s: %v", contract.Name, err)
+		}
+		contractABI, err := abi.JSON(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ABI of contract %s: %v", contract.Name, err)
+		}
+
+		registry.contracts[goEthCommon.HexToAddress(contract.Address)] = &registeredContract{name: contract.Name, abi: contractABI}
+		log.Printf("Registered ABI of contract %s at %s", contract.Name, contract.Address)
+	}
+	return registry, nil
+}
+
+// processDecodedEvents decodes the calldata and logs of the registered contracts in a block.
+// Calldata and logs that do not match the contract ABI are skipped.
+func processDecodedEvents(registry *ABIRegistry, block *goEthTypes.Block, receipts []*goEthTypes.Receipt) []*data.DecodedEvent {
+	events := make([]*data.DecodedEvent, 0)
+	if len(registry.contracts) == 0 {
+		return events
+	}
+
+	timestamp := time.Unix(int64(block.Time()), 0)
+	for _, tx := range block.Transactions() {
+		event, err := registry.decodeCalldata(tx)
+		if err != nil {
+			log.Printf("Skipping calldata of transaction %s: %v", tx.Hash().Hex(), err)
+		} else if event != nil {
+			event.BlockHash = block.Hash().Hex()
+			event.BlockNumber = block.NumberU64()
+			event.Timestamp = timestamp
+			events = append(events, event)
+		}
+	}
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			event, err := registry.decodeLog(l)
+			if err != nil {
+				log.Printf("Skipping log %d of block %d: %v", l.Index, block.NumberU64(), err)
+			} else if event != nil {
+				event.Timestamp = timestamp
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+// decodeCalldata decodes the method call of a transaction to a registered contract
+func (r *ABIRegistry) decodeCalldata(tx *goEthTypes.Transaction) (*data.DecodedEvent, error) {
+	if tx.To() == nil || len(tx.Data()) < 4 {
+		return nil, nil
+	}
+	contract, ok := r.contracts[*tx.To()]
+	if !ok {
+		return nil, nil
+	}
+
+	method, err := contract.abi.MethodById(tx.Data()[:4])
+	if err != nil {
+		return nil, err
+	}
+	args := make(map[string]interface{})
+	if err := method.Inputs.UnpackIntoMap(args, tx.Data()[4:]); err != nil {
+		return nil, fmt.Errorf("failed to unpack %s arguments: %v", method.Name, err)
+	}
+	encoded, err := encodeABIArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.DecodedEvent{
+		ID:              tx.Hash().Hex(),
+		TransactionHash: tx.Hash().Hex(),
+		ContractAddress: tx.To().Hex(),
+		ContractName:    contract.name,
+		Kind:            data.DecodedKindMethod,
+		Name:            method.Name,
+		Signature:       method.Sig,
+		Args:            encoded,
+	}, nil
+}
+
+// decodeLog decodes a log emitted by a registered contract
+func (r *ABIRegistry) decodeLog(l *goEthTypes.Log) (*data.DecodedEvent, error) {
+	contract, ok := r.contracts[l.Address]
+	if !ok || len(l.Topics) == 0 {
+		return nil, nil
+	}
+
+	event, err := contract.abi.EventByID(l.Topics[0])
+	if err != nil {
+		return nil, err
+	}
+	args := make(map[string]interface{})
+	if err := event.Inputs.NonIndexed().UnpackIntoMap(args, l.Data); err != nil {
+		return nil, fmt.Errorf("failed to unpack %s data: %v", event.Name, err)
+	}
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(args, indexed, l.Topics[1:]); err != nil {
+		return nil, fmt.Errorf("failed to parse %s topics: %v", event.Name, err)
+	}
+	encoded, err := encodeABIArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.DecodedEvent{
+		ID:              fmt.Sprintf("%s-%d", l.TxHash.Hex(), l.Index),
+		BlockHash:       l.BlockHash.Hex(),
+		BlockNumber:     l.BlockNumber,
+		TransactionHash: l.TxHash.Hex(),
+		LogIndex:        uint64(l.Index),
+		ContractAddress: l.Address.Hex(),
+		ContractName:    contract.name,
+		Kind:            data.DecodedKindEvent,
+		Name:            event.Name,
+		Signature:       event.Sig,
+		Args:            encoded,
+	}, nil
+}
+
+// encodeABIArgs encodes decoded arguments as JSON, with integers as decimal strings and bytes as hex
+func encodeABIArgs(args map[string]interface{}) (string, error) {
+	normalized := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		normalized[name] = normalizeABIValue(reflect.ValueOf(value))
+	}
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode arguments: %v", err)
+	}
+	return string(encoded), nil
+}
+
+func normalizeABIValue(value reflect.Value) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+	switch v := value.Interface().(type) {
+	case *big.Int:
+		return v.String()
+	case goEthCommon.Address:
+		return v.Hex()
+	case goEthCommon.Hash:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	}
+
+	switch value.Kind() {
+	case reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			bytes := make([]byte, value.Len())
+			reflect.Copy(reflect.ValueOf(bytes), value)
+			return hexutil.Encode(bytes)
+		}
+		fallthrough
+	case reflect.Slice:
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = normalizeABIValue(value.Index(i))
+		}
+		return items
+	case reflect.Struct:
+		fields := make(map[string]interface{}, value.NumField())
+		for i := 0; i < value.NumField(); i++ {
+			name := value.Type().Field(i).Tag.Get("json")
+			if name == "" {
+				name = value.Type().Field(i).Name
+			}
+			fields[name] = normalizeABIValue(value.Field(i))
+		}
+		return fields
+	}
+	return value.Interface()
+}
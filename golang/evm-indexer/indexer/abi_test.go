@@ -0,0 +1,206 @@
+package indexer
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	goEthCommon "github.com/ethereum/go-ethereum/common"
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/synkube/app/evm-indexer/config"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+const testABI = `[
+	{"type": "event", "name": "Transfer", "inputs": [
+		{"name": "from", "type": "address", "indexed": true},
+		{"name": "to", "type": "address", "indexed": true},
+		{"name": "value", "type": "uint256", "indexed": false}]},
+	{"type": "function", "name": "transfer", "inputs": [
+		{"name": "to", "type": "address"},
+		{"name": "amount", "type": "uint256"}]},
+	{"type": "function", "name": "setValues", "inputs": [
+		{"name": "key", "type": "bytes32"},
+		{"name": "values", "type": "uint256[]"}]}
+]`
+
+var registered = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000e1")
+
+// newTestRegistry registers testABI at the registered address
+func newTestRegistry(t *testing.T) (*ABIRegistry, abi.ABI) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := os.WriteFile(path, []byte(testABI), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewABIRegistry([]config.Contract{{Name: "Token", Address: registered.Hex(), ABI: path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry, registry.contracts[registered].abi
+}
+
+// call returns a transaction calling contract with calldata
+func call(contract goEthCommon.Address, calldata []byte) *goEthTypes.Transaction {
+	return goEthTypes.NewTx(&goEthTypes.LegacyTx{To: &contract, Gas: 100000, GasPrice: big.NewInt(1), Data: calldata})
+}
+
+func TestDecodeCalldata(t *testing.T) {
+	registry, contractABI := newTestRegistry(t)
+	transfer, err := contractABI.Pack("transfer", receiver, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setValues, err := contractABI.Pack("setValues", goEthCommon.HexToHash("0xab"), []*big.Int{big.NewInt(1), big.NewInt(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		tx       *goEthTypes.Transaction
+		wantName string
+		wantArgs string
+		wantErr  bool
+	}{
+		{
+			name:     "transfer",
+			tx:       call(registered, transfer),
+			wantName: "transfer",
+			wantArgs: `{"amount":"1000","to":"` + receiver.Hex() + `"}`,
+		},
+		{
+			name:     "bytes32 and array arguments",
+			tx:       call(registered, setValues),
+			wantName: "setValues",
+			wantArgs: `{"key":"` + goEthCommon.HexToHash("0xab").Hex() + `","values":["1","2"]}`,
+		},
+		{name: "unregistered contract", tx: call(token, transfer)},
+		{name: "contract deployment", tx: goEthTypes.NewTx(&goEthTypes.LegacyTx{Data: transfer})},
+		{name: "plain transfer", tx: call(registered, nil)},
+		{name: "unknown selector", tx: call(registered, []byte{0xde, 0xad, 0xbe, 0xef}), wantErr: true},
+		{name: "truncated arguments", tx: call(registered, transfer[:len(transfer)-32]), wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event, err := registry.decodeCalldata(test.tx)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %t", err, test.wantErr)
+			}
+			if test.wantName == "" {
+				if event != nil {
+					t.Errorf("decoded %+v, want nothing", event)
+				}
+				return
+			}
+			if event == nil {
+				t.Fatal("nothing decoded")
+			}
+			if event.Kind != data.DecodedKindMethod || event.Name != test.wantName || event.ContractName != "Token" {
+				t.Errorf("decoded %s %s of %s, want method %s of Token", event.Kind, event.Name, event.ContractName, test.wantName)
+			}
+			if event.Args != test.wantArgs {
+				t.Errorf("arguments = %s, want %s", event.Args, test.wantArgs)
+			}
+			if event.ID != test.tx.Hash().Hex() || event.ContractAddress != registered.Hex() {
+				t.Errorf("decoded %s at %s, want %s at %s", event.ID, event.ContractAddress, test.tx.Hash().Hex(), registered.Hex())
+			}
+		})
+	}
+}
+
+func TestDecodeLog(t *testing.T) {
+	registry, contractABI := newTestRegistry(t)
+	transferEvent := contractABI.Events["Transfer"]
+	value, err := transferEvent.Inputs.NonIndexed().Pack(big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := addressTopic(sender), addressTopic(receiver)
+	eventLog := func(address goEthCommon.Address, data []byte, topics ...goEthCommon.Hash) *goEthTypes.Log {
+		return &goEthTypes.Log{Address: address, Topics: topics, Data: data, BlockNumber: 5, TxHash: txHash, Index: 3}
+	}
+
+	tests := []struct {
+		name     string
+		log      *goEthTypes.Log
+		wantArgs string
+		wantErr  bool
+	}{
+		{
+			name:     "Transfer",
+			log:      eventLog(registered, value, transferEvent.ID, from, to),
+			wantArgs: `{"from":"` + sender.Hex() + `","to":"` + receiver.Hex() + `","value":"1000"}`,
+		},
+		{name: "unregistered contract", log: eventLog(token, value, transferEvent.ID, from, to)},
+		{name: "anonymous event", log: eventLog(registered, value)},
+		{name: "unknown event", log: eventLog(registered, value, goEthCommon.HexToHash("0x1234"), from, to), wantErr: true},
+		{name: "missing data", log: eventLog(registered, nil, transferEvent.ID, from, to), wantErr: true},
+		{name: "missing topic", log: eventLog(registered, value, transferEvent.ID, from), wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event, err := registry.decodeLog(test.log)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %t", err, test.wantErr)
+			}
+			if test.wantArgs == "" {
+				if event != nil {
+					t.Errorf("decoded %+v, want nothing", event)
+				}
+				return
+			}
+			if event == nil {
+				t.Fatal("nothing decoded")
+			}
+			if event.Kind != data.DecodedKindEvent || event.Name != "Transfer" || event.Signature != "Transfer(address,address,uint256)" {
+				t.Errorf("decoded %s %s, want event Transfer(address,address,uint256)", event.Kind, event.Signature)
+			}
+			if event.Args != test.wantArgs {
+				t.Errorf("arguments = %s, want %s", event.Args, test.wantArgs)
+			}
+			if event.ID != txHash.Hex()+"-3" || event.LogIndex != 3 || event.BlockNumber != 5 {
+				t.Errorf("decoded %s at log %d of block %d, want log 3 of block 5", event.ID, event.LogIndex, event.BlockNumber)
+			}
+		})
+	}
+}
+
+func TestProcessDecodedEventsSkipsUndecodable(t *testing.T) {
+	registry, contractABI := newTestRegistry(t)
+	transfer, err := contractABI.Pack("transfer", receiver, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs := []*goEthTypes.Transaction{call(registered, transfer), call(registered, []byte{0xde, 0xad, 0xbe, 0xef})}
+	block := goEthTypes.NewBlockWithHeader(&goEthTypes.Header{Number: big.NewInt(5), Time: 1700000000}).
+		WithBody(goEthTypes.Body{Transactions: txs})
+	receipts := []*goEthTypes.Receipt{{Logs: []*goEthTypes.Log{{Address: registered, Topics: []goEthCommon.Hash{goEthCommon.HexToHash("0x1234")}}}}}
+
+	events := processDecodedEvents(registry, block, receipts)
+	if len(events) != 1 || events[0].Name != "transfer" {
+		t.Fatalf("decoded %d events, want the transfer call only", len(events))
+	}
+	if events[0].BlockHash != block.Hash().Hex() || events[0].BlockNumber != 5 || events[0].Timestamp.Unix() != 1700000000 {
+		t.Errorf("call decoded in block %s %d at %s, want the block", events[0].BlockHash, events[0].BlockNumber, events[0].Timestamp)
+	}
+}
+
+func TestNewABIRegistryRejectsInvalidContracts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(path, []byte(strings.TrimSuffix(testABI, "]")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, contract := range []config.Contract{
+		{Name: "BadAddress", Address: "0x1234", ABI: path},
+		{Name: "Missing", Address: registered.Hex(), ABI: filepath.Join(t.TempDir(), "missing.json")},
+		{Name: "Invalid", Address: registered.Hex(), ABI: path},
+	} {
+		if _, err := NewABIRegistry([]config.Contract{contract}); err == nil {
+			t.Errorf("contract %s registered, want an error", contract.Name)
+		}
+	}
+}
@@ -2,32 +2,67 @@ package indexer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ava-labs/coreth/rpc"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	ethRpc "github.com/ethereum/go-ethereum/rpc"
 	coreData "github.com/synkube/app/core/data"
 )
 
+// methodNotFoundCode is the JSON-RPC error code returned for unsupported methods
+const methodNotFoundCode = -32601
+
 type BlockManager struct {
 	sync.Mutex
 	currentBlock int
 	maxBlock     int
+	follow       bool
 	missedBlocks map[int]struct{}
+	retryBlocks  map[int]time.Time // Failed blocks and when to retry them
+	failures     map[int]int       // Number of failed attempts per block
 }
 
-// NewBlockManager creates a new BlockManager
-func NewBlockManager(startBlock, maxBlock int) *BlockManager {
+// NewBlockManager creates a new BlockManager. When follow is set, maxBlock is
+// extended with SetMaxBlock as the chain head advances.
+func NewBlockManager(startBlock, maxBlock int, follow bool) *BlockManager {
 	return &BlockManager{
 		currentBlock: startBlock,
 		maxBlock:     maxBlock,
+		follow:       follow,
 		missedBlocks: make(map[int]struct{}),
+		retryBlocks:  make(map[int]time.Time),
+		failures:     make(map[int]int),
+	}
+}
+
+// Done reports whether all blocks have been handed out and no retry is pending
+func (bm *BlockManager) Done() bool {
+	bm.Lock()
+	defer bm.Unlock()
+	return !bm.follow && len(bm.missedBlocks) == 0 && len(bm.retryBlocks) == 0 && bm.currentBlock > bm.maxBlock
+}
+
+// Following reports whether the manager keeps extending its range with the chain head
+func (bm *BlockManager) Following() bool {
+	return bm.follow
+}
+
+// SetMaxBlock raises the last block to be indexed, lower values are ignored
+func (bm *BlockManager) SetMaxBlock(maxBlock int) {
+	bm.Lock()
+	defer bm.Unlock()
+	if maxBlock > bm.maxBlock {
+		bm.maxBlock = maxBlock
 	}
 }
 
@@ -52,6 +87,41 @@ func (bm *BlockManager) GetNextBlock() (int, bool) {
 	return 0, false
 }
 
+// GetNextBlocks returns up to count blocks to be indexed in ascending order, blocks due for a retry and missed blocks first
+func (bm *BlockManager) GetNextBlocks(count int) ([]int, bool) {
+	bm.Lock()
+	defer bm.Unlock()
+
+	blocks := make([]int, 0, count)
+	now := time.Now()
+	for block, retryAt := range bm.retryBlocks {
+		if len(blocks) == count {
+			break
+		}
+		if retryAt.After(now) {
+			continue
+		}
+		delete(bm.retryBlocks, block)
+		blocks = append(blocks, block)
+	}
+
+	for block := range bm.missedBlocks {
+		if len(blocks) == count {
+			break
+		}
+		delete(bm.missedBlocks, block)
+		blocks = append(blocks, block)
+	}
+
+	for len(blocks) < count && bm.currentBlock <= bm.maxBlock {
+		blocks = append(blocks, bm.currentBlock)
+		bm.currentBlock++
+	}
+
+	sort.Ints(blocks)
+	return blocks, len(blocks) > 0
+}
+
 // AddMissedBlock adds a missed block to be re-indexed
 func (bm *BlockManager) AddMissedBlock(block int) {
 	bm.Lock()
@@ -68,22 +138,71 @@ func (bm *BlockManager) AddMissedBlocks(blocks []int) {
 	}
 }
 
+// RecordFailure counts a failed attempt to index a block and returns the number of failures so far
+func (bm *BlockManager) RecordFailure(block int) int {
+	bm.Lock()
+	defer bm.Unlock()
+	bm.failures[block]++
+	return bm.failures[block]
+}
+
+// ClearFailures forgets the failed attempts of a block once it is indexed or given up on
+func (bm *BlockManager) ClearFailures(block int) {
+	bm.Lock()
+	defer bm.Unlock()
+	delete(bm.failures, block)
+}
+
+// QueueDepth returns the number of blocks left in the range, missed blocks and failed blocks
+// waiting for a retry
+func (bm *BlockManager) QueueDepth() (pending, missed, retry int) {
+	bm.Lock()
+	defer bm.Unlock()
+	if bm.currentBlock <= bm.maxBlock {
+		pending = bm.maxBlock - bm.currentBlock + 1
+	}
+	return pending, len(bm.missedBlocks), len(bm.retryBlocks)
+}
+
+// RetryBlock re-queues a failed block to be handed out again after the delay
+func (bm *BlockManager) RetryBlock(block int, delay time.Duration) {
+	bm.Lock()
+	defer bm.Unlock()
+	bm.retryBlocks[block] = time.Now().Add(delay)
+}
+
 type RPCClient struct {
 	rpcs          []coreData.RPC
 	currentRPCIdx int
 	maxRetries    int
 	client        *ethclient.Client
 	mutex         sync.Mutex // To ensure thread-safe access to currentRPCIdx
+	// noBlockReceipts is set once the node rejects eth_getBlockReceipts
+	noBlockReceipts atomic.Bool
+	// noBatch is set once the provider rejects JSON-RPC batch requests
+	noBatch atomic.Bool
+	// batchFailures counts the batch requests that failed as a whole in a row
+	batchFailures atomic.Int32
+	// maxBatchItems is the most calls sent in one batch request, lowered when the provider refuses
+	// a batch for its size
+	maxBatchItems atomic.Int32
+	// noTrace is set once the node rejects debug_traceBlockByNumber
+	noTrace atomic.Bool
 }
 
-// NewRPCClient creates a new RPCClient instance
-func NewRPCClient(rpcs []coreData.RPC, maxRetries int) (*RPCClient, error) {
+// NewRPCClient creates a new RPCClient instance sending at most maxBatchItems calls per batch
+// request, or defaultMaxBatchItems when it is not positive. It gives up on connecting once ctx is done.
+func NewRPCClient(ctx context.Context, rpcs []coreData.RPC, maxRetries, maxBatchItems int) (*RPCClient, error) {
 	rpcClient := &RPCClient{
 		rpcs:       rpcs,
 		maxRetries: maxRetries,
 	}
+	if maxBatchItems <= 0 {
+		maxBatchItems = defaultMaxBatchItems
+	}
+	rpcClient.maxBatchItems.Store(int32(maxBatchItems))
 
-	client, err := rpcClient.connectWithRetry()
+	client, err := rpcClient.connectWithRetry(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -92,13 +211,13 @@ func NewRPCClient(rpcs []coreData.RPC, maxRetries int) (*RPCClient, error) {
 	return rpcClient, nil
 }
 
-func (rpcClient *RPCClient) connectWithRetry() (*ethclient.Client, error) {
+func (rpcClient *RPCClient) connectWithRetry(ctx context.Context) (*ethclient.Client, error) {
 	var client *ethclient.Client
 	var err error
 	for i := 0; i < len(rpcClient.rpcs); i++ {
 		url := rpcClient.rpcs[i].URL
 		for retry := 0; retry < rpcClient.maxRetries; retry++ {
-			client, err = ethclient.Dial(url)
+			client, err = ethclient.DialContext(ctx, url)
 			if err == nil {
 				// Successfully connected
 				rpcClient.mutex.Lock()
@@ -112,13 +231,15 @@ func (rpcClient *RPCClient) connectWithRetry() (*ethclient.Client, error) {
 				break
 			}
 			log.Printf("Failed to connect to Ethereum client (%s): %v. Retrying (%d/%d)...", url, err, retry+1, rpcClient.maxRetries)
-			time.Sleep(2 * time.Second)
+			if err := sleep(ctx, 2*time.Second); err != nil {
+				return nil, err
+			}
 		}
 	}
 	return nil, fmt.Errorf("failed to connect to any Ethereum client after %d retries", rpcClient.maxRetries*len(rpcClient.rpcs))
 }
 
-func (rpcClient *RPCClient) switchRPC() error {
+func (rpcClient *RPCClient) switchRPC(ctx context.Context) error {
 	rpcClient.mutex.Lock()
 	defer rpcClient.mutex.Unlock()
 
@@ -126,7 +247,7 @@ func (rpcClient *RPCClient) switchRPC() error {
 	if rpcClient.currentRPCIdx >= len(rpcClient.rpcs) {
 		rpcClient.currentRPCIdx = 0
 	}
-	client, err := rpcClient.connectWithRetry()
+	client, err := rpcClient.connectWithRetry(ctx)
 	if err != nil {
 		return err
 	}
@@ -134,10 +255,32 @@ func (rpcClient *RPCClient) switchRPC() error {
 	return nil
 }
 
-func (rpcClient *RPCClient) retry(f func() error) error {
+// endpoint returns the metrics label of the RPC in use
+func (rpcClient *RPCClient) endpoint() string {
+	rpcClient.mutex.Lock()
+	defer rpcClient.mutex.Unlock()
+	return endpointLabel(rpcClient.rpcs[rpcClient.currentRPCIdx].URL)
+}
+
+// Ping checks that the RPC in use answers, without retrying
+func (rpcClient *RPCClient) Ping(ctx context.Context) error {
+	start := time.Now()
+	_, err := rpcClient.client.BlockNumber(ctx)
+	observeRPC("eth_blockNumber", rpcClient.endpoint(), start, err)
+	return err
+}
+
+// retry calls f until it succeeds, the retries are exhausted or ctx is done, recording each
+// attempt as a request of the JSON-RPC method
+func (rpcClient *RPCClient) retry(ctx context.Context, method string, f func() error) error {
 	var err error
 	for retry := 0; retry < rpcClient.maxRetries; retry++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		start := time.Now()
 		err = f()
+		observeRPC(method, rpcClient.endpoint(), start, err)
 		if err == nil {
 			// Successfully executed the function
 			return nil
@@ -145,44 +288,151 @@ func (rpcClient *RPCClient) retry(f func() error) error {
 		// Check if the error is due to rate limiting (HTTP 429)
 		if rpcErr, ok := err.(*rpc.HTTPError); ok && rpcErr.StatusCode == 429 {
 			log.Printf("RPC rate limited (429). Switching to next RPC URL.")
-			err = rpcClient.switchRPC()
+			err = rpcClient.switchRPC(ctx)
 			if err != nil {
 				return err
 			}
 			continue
 		}
 		log.Printf("Error executing function: %v. Retrying...", err)
-		time.Sleep(2 * time.Second)
+		if ctxErr := sleep(ctx, 2*time.Second); ctxErr != nil {
+			return ctxErr
+		}
 	}
 	return err
 }
 
-func (rpcClient *RPCClient) GetBlockWithRetry(blockNumber uint64) (*types.Block, error) {
+// sleep waits for d, returning early with the error of ctx once it is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (rpcClient *RPCClient) GetBlockWithRetry(ctx context.Context, blockNumber uint64) (*types.Block, error) {
 	var block *types.Block
-	err := rpcClient.retry(func() error {
+	err := rpcClient.retry(ctx, "eth_getBlockByNumber", func() error {
 		var err error
-		block, err = rpcClient.client.BlockByNumber(context.Background(), big.NewInt(int64(blockNumber)))
+		block, err = rpcClient.client.BlockByNumber(ctx, big.NewInt(int64(blockNumber)))
 		return err
 	})
 	return block, err
 }
 
-func (rpcClient *RPCClient) GetBlockByHashWithRetry(hash common.Hash) (*types.Block, error) {
+func (rpcClient *RPCClient) GetBlockByHashWithRetry(ctx context.Context, hash common.Hash) (*types.Block, error) {
 	var block *types.Block
-	err := rpcClient.retry(func() error {
+	err := rpcClient.retry(ctx, "eth_getBlockByHash", func() error {
 		var err error
-		block, err = rpcClient.client.BlockByHash(context.Background(), hash)
+		block, err = rpcClient.client.BlockByHash(ctx, hash)
 		return err
 	})
 	return block, err
 }
 
-func (rpcClient *RPCClient) GetBalanceWithRetry(account common.Address) (*big.Int, error) {
+func (rpcClient *RPCClient) GetLatestBlockNumberWithRetry(ctx context.Context) (uint64, error) {
+	var number uint64
+	err := rpcClient.retry(ctx, "eth_blockNumber", func() error {
+		var err error
+		number, err = rpcClient.client.BlockNumber(ctx)
+		return err
+	})
+	return number, err
+}
+
+// GetCodeWithRetry retrieves the bytecode of a contract at a block
+func (rpcClient *RPCClient) GetCodeWithRetry(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	var code []byte
+	err := rpcClient.retry(ctx, "eth_getCode", func() error {
+		var err error
+		code, err = rpcClient.client.CodeAt(ctx, account, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+// GetChainIDWithRetry retrieves the chain ID of the node
+func (rpcClient *RPCClient) GetChainIDWithRetry(ctx context.Context) (*big.Int, error) {
+	var chainID *big.Int
+	err := rpcClient.retry(ctx, "eth_chainId", func() error {
+		var err error
+		chainID, err = rpcClient.client.ChainID(ctx)
+		return err
+	})
+	return chainID, err
+}
+
+func (rpcClient *RPCClient) GetBalanceWithRetry(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
 	var balance *big.Int
-	err := rpcClient.retry(func() error {
+	err := rpcClient.retry(ctx, "eth_getBalance", func() error {
 		var err error
-		balance, err = rpcClient.client.BalanceAt(context.Background(), account, nil)
+		balance, err = rpcClient.client.BalanceAt(ctx, account, blockNumber)
 		return err
 	})
 	return balance, err
 }
+
+func (rpcClient *RPCClient) GetTransactionReceiptWithRetry(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := rpcClient.retry(ctx, "eth_getTransactionReceipt", func() error {
+		var err error
+		receipt, err = rpcClient.client.TransactionReceipt(ctx, hash)
+		return err
+	})
+	return receipt, err
+}
+
+// GetBlockReceiptsWithRetry retrieves all receipts of a block with eth_getBlockReceipts.
+// It returns nil receipts without an error when the node does not support the method.
+func (rpcClient *RPCClient) GetBlockReceiptsWithRetry(ctx context.Context, hash common.Hash) ([]*types.Receipt, error) {
+	var receipts []*types.Receipt
+	err := rpcClient.retry(ctx, "eth_getBlockReceipts", func() error {
+		var err error
+		receipts, err = rpcClient.client.BlockReceipts(ctx, ethRpc.BlockNumberOrHashWithHash(hash, false))
+		if isMethodNotFound(err) {
+			rpcClient.noBlockReceipts.Store(true)
+			receipts = nil
+			return nil
+		}
+		return err
+	})
+	return receipts, err
+}
+
+// GetReceiptsWithRetry retrieves the receipts of all transactions in a block, using
+// eth_getBlockReceipts when the node supports it and one call per transaction otherwise.
+func (rpcClient *RPCClient) GetReceiptsWithRetry(ctx context.Context, block *types.Block) ([]*types.Receipt, error) {
+	if !rpcClient.noBlockReceipts.Load() {
+		receipts, err := rpcClient.GetBlockReceiptsWithRetry(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if !rpcClient.noBlockReceipts.Load() {
+			if err := checkReceipts(block, receipts); err != nil {
+				return nil, err
+			}
+			return receipts, nil
+		}
+		log.Printf("eth_getBlockReceipts is not supported, falling back to per transaction receipts")
+	}
+
+	receipts := make([]*types.Receipt, 0, block.Transactions().Len())
+	for _, tx := range block.Transactions() {
+		receipt, err := rpcClient.GetTransactionReceiptWithRetry(ctx, tx.Hash())
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// isMethodNotFound checks if the node rejected a call because it does not support the method
+func isMethodNotFound(err error) bool {
+	var rpcErr ethRpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode
+}
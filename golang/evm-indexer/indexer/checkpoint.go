@@ -0,0 +1,134 @@
+package indexer
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+// checkpoint tracks the indexed blocks of a chain and persists its water marks, so that
+// restarts resume without scanning every block from the start block
+type checkpoint struct {
+	sync.Mutex
+	bds   *data.BlockchainDataStore
+	state data.IndexerState
+	done  map[uint64]struct{} // Processed blocks above the low-water mark
+}
+
+// loadCheckpoint retrieves the indexer state of a chain. A chain without state starts at the
+// start block with the latest saved block as high-water mark.
+func loadCheckpoint(bds *data.BlockchainDataStore, chainID uint64, startBlock uint64) (*checkpoint, error) {
+	state, err := bds.GetIndexerState(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		latestSavedBlock, err := bds.GetLatestSavedBlock()
+		if err != nil {
+			return nil, err
+		}
+		state = &data.IndexerState{ChainID: chainID, LowWaterMark: startBlock, HighWaterMark: latestSavedBlock}
+	}
+	if state.LowWaterMark < startBlock {
+		state.LowWaterMark = startBlock
+	}
+	return &checkpoint{bds: bds, state: *state, done: make(map[uint64]struct{})}, nil
+}
+
+// resume finds the blocks missing between the water marks, up to endBlock, and moves the low-water
+// mark to the first of them. The stored blocks above it are marked done so that the low-water mark
+// advances past them once the missing blocks are indexed. It returns the missing blocks and the
+// block to continue the range from.
+func (c *checkpoint) resume(endBlock uint64) ([]int, uint64, error) {
+	c.Lock()
+	defer c.Unlock()
+	low, high := c.state.LowWaterMark, c.state.HighWaterMark
+	if high > endBlock {
+		high = endBlock
+	}
+	if high < low {
+		return nil, low, nil
+	}
+
+	missing, err := c.bds.IdentifyMissingBlocks(low, high)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(missing) == 0 {
+		c.state.LowWaterMark = high + 1
+		return missing, high + 1, nil
+	}
+
+	gaps := make(map[uint64]struct{}, len(missing))
+	for _, number := range missing {
+		gaps[uint64(number)] = struct{}{}
+	}
+	first := uint64(missing[0])
+	for number := first + 1; number <= high; number++ {
+		if _, ok := gaps[number]; !ok {
+			c.done[number] = struct{}{}
+		}
+	}
+	c.state.LowWaterMark = first
+	return missing, high + 1, nil
+}
+
+// markDone records blocks that were indexed or recorded as failed and advances the low-water
+// mark past contiguous blocks
+func (c *checkpoint) markDone(blockNumbers []int) {
+	c.Lock()
+	defer c.Unlock()
+	for _, blockNumber := range blockNumbers {
+		number := uint64(blockNumber)
+		if number < c.state.LowWaterMark {
+			continue
+		}
+		c.done[number] = struct{}{}
+		if number > c.state.HighWaterMark {
+			c.state.HighWaterMark = number
+		}
+	}
+	for {
+		if _, ok := c.done[c.state.LowWaterMark]; !ok {
+			break
+		}
+		delete(c.done, c.state.LowWaterMark)
+		c.state.LowWaterMark++
+	}
+}
+
+// rewind lowers the low-water mark below blocks that were rolled back by a reorg
+func (c *checkpoint) rewind(blockNumbers []int) {
+	c.Lock()
+	defer c.Unlock()
+	for _, blockNumber := range blockNumbers {
+		number := uint64(blockNumber)
+		delete(c.done, number)
+		if number < c.state.LowWaterMark {
+			// Blocks between the new and old low-water mark are still done
+			for n := number + 1; n < c.state.LowWaterMark; n++ {
+				c.done[n] = struct{}{}
+			}
+			c.state.LowWaterMark = number
+		}
+	}
+}
+
+// lowWaterMark returns the first block that is not indexed yet
+func (c *checkpoint) lowWaterMark() uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.state.LowWaterMark
+}
+
+// save persists the water marks, unless ctx is done first
+func (c *checkpoint) save(ctx context.Context) {
+	c.Lock()
+	defer c.Unlock()
+	state := c.state
+	if err := c.bds.WithContext(ctx).SaveIndexerState(&state); err != nil {
+		log.Printf("Failed to save indexer state: %v", err)
+	}
+}
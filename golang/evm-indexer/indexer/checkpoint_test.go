@@ -0,0 +1,136 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	coreData "github.com/synkube/app/core/data"
+	"github.com/synkube/app/evm-indexer/config"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+// newTestStore creates a store of chain 1 on a fresh in-memory SQLite database
+func newTestStore(t *testing.T) *data.BlockchainDataStore {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	ds := data.Initialize(&config.Config{DbConfig: coreData.DbConfig{
+		Type:   "sqlite",
+		SQLite: coreData.SQLiteConfig{File: fmt.Sprintf("file:%s?mode=memory&cache=shared", name)},
+	}})
+	sqlDB, err := ds.DB().DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return data.NewBlockchainDataStore(ds, 0).ForChain(1)
+}
+
+// saveBlocks stores empty blocks with the given numbers
+func saveBlocks(t *testing.T, bds *data.BlockchainDataStore, numbers ...uint64) {
+	t.Helper()
+	for _, number := range numbers {
+		hash := fmt.Sprintf("0x%064x", number)
+		if err := bds.SaveBlock(&data.IndexedBlock{Block: &data.Block{ID: hash, Hash: hash, Number: number}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckpointResumeAdvancesPastGaps(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		state *data.IndexerState // Saved before the restart, nil for a database without state
+	}{
+		{"without state", nil},
+		{"with state", &data.IndexerState{ChainID: 1, LowWaterMark: 10, HighWaterMark: 14}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			bds := newTestStore(t)
+			saveBlocks(t, bds, 10, 11, 13, 14)
+			if test.state != nil {
+				if err := bds.SaveIndexerState(test.state); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cp, err := loadCheckpoint(bds, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			missing, next, err := cp.resume(100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(missing, []int{12}) || next != 15 {
+				t.Fatalf("resume = %v, %d, want [12], 15", missing, next)
+			}
+			if low := cp.lowWaterMark(); low != 12 {
+				t.Errorf("low-water mark = %d before indexing the gap, want 12", low)
+			}
+
+			cp.markDone([]int{12})
+			if low := cp.lowWaterMark(); low != 15 {
+				t.Errorf("low-water mark = %d after indexing the gap, want 15", low)
+			}
+			cp.markDone([]int{15})
+			cp.save(context.Background())
+
+			state, err := bds.GetIndexerState(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.LowWaterMark != 16 || state.HighWaterMark != 15 {
+				t.Errorf("saved water marks %d and %d, want 16 and 15", state.LowWaterMark, state.HighWaterMark)
+			}
+		})
+	}
+}
+
+func TestCheckpointResumeWithoutGaps(t *testing.T) {
+	bds := newTestStore(t)
+	saveBlocks(t, bds, 10, 11, 12)
+
+	cp, err := loadCheckpoint(bds, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, next, err := cp.resume(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 || next != 13 {
+		t.Fatalf("resume = %v, %d, want no missing blocks and 13", missing, next)
+	}
+	if low := cp.lowWaterMark(); low != 13 {
+		t.Errorf("low-water mark = %d, want 13", low)
+	}
+}
+
+func TestLagRecoversAfterResumeWithGaps(t *testing.T) {
+	bds := newTestStore(t)
+	saveBlocks(t, bds, 10, 11, 13, 14)
+	if err := bds.SaveIndexerState(&data.IndexerState{ChainID: 1, LowWaterMark: 10, HighWaterMark: 14}); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := loadCheckpoint(bds, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cp.resume(100); err != nil {
+		t.Fatal(err)
+	}
+	p := &pipeline{checkpoint: cp}
+	p.head.Store(14)
+	if lag, ok := p.lag(); !ok || lag != 3 {
+		t.Errorf("lag = %d, %v before indexing the gap, want 3 blocks from block 12", lag, ok)
+	}
+
+	cp.markDone([]int{12})
+	if lag, ok := p.lag(); !ok || lag != 0 {
+		t.Errorf("lag = %d, %v after indexing the gap, want 0", lag, ok)
+	}
+}
@@ -0,0 +1,78 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	goEthCommon "github.com/ethereum/go-ethereum/common"
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+// createTypes are the call tracer types of calls that deploy a contract
+var createTypes = map[string]bool{"CREATE": true, "CREATE2": true}
+
+// processContracts records the contracts deployed in a block, by transactions without a recipient
+// and, when tracing is on, by other contracts. The deploying transactions get the contract address.
+// The bytecode hash is taken from the code returned by the deployment in the trace, or from the
+// code of the contract at the end of the block without a trace.
+func processContracts(ctx context.Context, rpcClient *RPCClient, block *goEthTypes.Block, transactions []*data.Transaction, receipts []*goEthTypes.Receipt, deployments []deployment) ([]*data.Contract, error) {
+	txByHash := make(map[string]*data.Transaction, len(transactions))
+	for _, tx := range transactions {
+		txByHash[tx.ID] = tx
+	}
+	txCode := make(map[string][]byte)
+	for _, deployed := range deployments {
+		if !deployed.internal {
+			txCode[deployed.txHash] = deployed.code
+		}
+	}
+
+	contracts := make([]*data.Contract, 0)
+	seen := make(map[goEthCommon.Address]bool)
+	addContract := func(address goEthCommon.Address, creator, txHash string, code []byte) error {
+		// A contract can be deployed twice in a block after destroying itself, the block ends with the last one
+		if seen[address] {
+			for i, contract := range contracts {
+				if contract.Address == address.Hex() {
+					contracts = append(contracts[:i], contracts[i+1:]...)
+					break
+				}
+			}
+		}
+		seen[address] = true
+
+		if code == nil {
+			var err error
+			if code, err = rpcClient.GetCodeWithRetry(ctx, address, block.Number()); err != nil {
+				log.Printf("Failed to retrieve code of contract %s: %v", address.Hex(), err)
+				return fmt.Errorf("failed to retrieve code of contract %s: %v", address.Hex(), err)
+			}
+		}
+		contracts = append(contracts, data.CreateContractData(address.Hex(), creator, txHash, block, code))
+		return nil
+	}
+
+	for _, receipt := range receipts {
+		tx, ok := txByHash[receipt.TxHash.Hex()]
+		if !ok || tx.ToAddress != nil || receipt.Status != goEthTypes.ReceiptStatusSuccessful {
+			continue
+		}
+		tx.CreatedContract = receipt.ContractAddress.Hex()
+		if err := addContract(receipt.ContractAddress, tx.FromAddress, tx.ID, txCode[tx.ID]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Deployments within reverted frames or transactions are left out of the trace deployments
+	for _, deployed := range deployments {
+		if !deployed.internal {
+			continue
+		}
+		if err := addContract(deployed.address, deployed.creator, deployed.txHash, deployed.code); err != nil {
+			return nil, err
+		}
+	}
+	return contracts, nil
+}
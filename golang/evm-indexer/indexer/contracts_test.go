@@ -0,0 +1,113 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	goEthCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+var (
+	deployer  = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000d1")
+	factory   = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000f1")
+	created   = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000c1")
+	reverted  = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000c2")
+	destroyed = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000c3")
+)
+
+// testBlock returns a block of the transactions, without recipient when to is nil, with their
+// indexed form and successful receipts
+func testBlock(to ...*goEthCommon.Address) (*goEthTypes.Block, []*data.Transaction, []*goEthTypes.Receipt) {
+	txs := make([]*goEthTypes.Transaction, len(to))
+	for i := range txs {
+		txs[i] = goEthTypes.NewTx(&goEthTypes.LegacyTx{Nonce: uint64(i), To: to[i], Gas: 21000, GasPrice: big.NewInt(1)})
+	}
+	block := goEthTypes.NewBlockWithHeader(&goEthTypes.Header{Number: big.NewInt(5), Time: 1700000000}).
+		WithBody(goEthTypes.Body{Transactions: txs})
+
+	transactions := make([]*data.Transaction, len(txs))
+	receipts := make([]*goEthTypes.Receipt, len(txs))
+	for i, tx := range txs {
+		transactions[i] = &data.Transaction{ID: tx.Hash().Hex(), FromAddress: deployer.Hex()}
+		if to[i] != nil {
+			recipient := to[i].Hex()
+			transactions[i].ToAddress = &recipient
+		}
+		receipts[i] = &goEthTypes.Receipt{TxHash: tx.Hash(), Status: goEthTypes.ReceiptStatusSuccessful}
+	}
+	return block, transactions, receipts
+}
+
+func TestProcessContractsSkipsDeploymentsUnderRevertedFrames(t *testing.T) {
+	block, transactions, receipts := testBlock(&factory)
+	code := hexutil.Bytes{0x60, 0x80}
+	// The factory catches the revert of its first call, which deployed a contract before reverting
+	traces := []TxTrace{{Result: CallFrame{Type: "CALL", From: deployer, To: &factory, Calls: []CallFrame{
+		{Type: "CALL", From: factory, To: &factory, Error: "execution reverted", Calls: []CallFrame{
+			{Type: "CREATE", From: factory, To: &reverted, Output: code},
+		}},
+		{Type: "CREATE2", From: factory, To: &created, Output: code},
+	}}}}
+
+	internalTxs, deployments, err := processInternalTransactions(block, traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(internalTxs) != 3 {
+		t.Errorf("got %d internal transactions, want 3", len(internalTxs))
+	}
+	contracts, err := processContracts(context.Background(), nil, block, transactions, receipts, deployments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts) != 1 || contracts[0].Address != created.Hex() {
+		t.Fatalf("contracts = %+v, want %s only", contracts, created.Hex())
+	}
+	if want := crypto.Keccak256Hash(code).Hex(); contracts[0].BytecodeHash != want {
+		t.Errorf("bytecode hash = %s, want %s of the CREATE2 output", contracts[0].BytecodeHash, want)
+	}
+}
+
+func TestProcessContractsHashesTheDeployedCode(t *testing.T) {
+	block, transactions, receipts := testBlock(nil)
+	receipts[0].ContractAddress = destroyed
+	code := hexutil.Bytes{0x60, 0x80, 0x60, 0x40}
+	// The contract destroys itself in its constructor, leaving no code at the end of the block
+	traces := []TxTrace{{Result: CallFrame{Type: "CREATE", From: deployer, To: &destroyed, Output: code}}}
+
+	_, deployments, err := processInternalTransactions(block, traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contracts, err := processContracts(context.Background(), nil, block, transactions, receipts, deployments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts) != 1 || contracts[0].BytecodeHash != crypto.Keccak256Hash(code).Hex() {
+		t.Errorf("contracts = %+v, want %s with the hash of the code it was deployed with", contracts, destroyed.Hex())
+	}
+	if transactions[0].CreatedContract != destroyed.Hex() {
+		t.Errorf("created contract = %s, want %s", transactions[0].CreatedContract, destroyed.Hex())
+	}
+}
+
+func TestProcessContractsWithoutCodeHasNoHash(t *testing.T) {
+	rpc := newFakeRPC(t, 0)
+	client := newFakeRPCClient(t, rpc, 0)
+	block, transactions, receipts := testBlock(nil)
+	receipts[0].ContractAddress = destroyed
+
+	// Without a trace the code is retrieved at the end of the block, where the contract is gone
+	contracts, err := processContracts(context.Background(), client, block, transactions, receipts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts) != 1 || contracts[0].BytecodeHash != "" {
+		t.Errorf("contracts = %+v, want %s without bytecode hash", contracts, destroyed.Hex())
+	}
+}
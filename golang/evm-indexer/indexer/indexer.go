@@ -1,86 +1,470 @@
 package indexer
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	goEthCommon "github.com/ethereum/go-ethereum/common"
 	goEthTypes "github.com/ethereum/go-ethereum/core/types"
 	coreData "github.com/synkube/app/core/data"
 	"github.com/synkube/app/core/evm"
+	"github.com/synkube/app/core/ginhelper"
 	"github.com/synkube/app/evm-indexer/config"
 	"github.com/synkube/app/evm-indexer/data"
 )
 
-// Worker function for goroutines to index blocks
-func worker(id int, bm *BlockManager, rpcClient *RPCClient, bds *data.BlockchainDataStore, wg *sync.WaitGroup) {
+const (
+	defaultPollInterval    = 5 * time.Second
+	defaultMaxBlockRetries = 5
+	defaultRetryInterval   = 2 * time.Second
+	defaultRetryBackoff    = 2
+	defaultDrainTimeout    = 30 * time.Second
+	maxRetryDelay          = 10 * time.Minute
+)
+
+// pipeline holds what the workers need to index the blocks of a chain
+type pipeline struct {
+	bm            *BlockManager
+	checkpoint    *checkpoint
+	rpcClient     *RPCClient
+	bds           *data.BlockchainDataStore
+	registry      *ABIRegistry
+	pollInterval  time.Duration
+	maxReorgDepth int
+	batchSize     int
+
+	maxBlockRetries int
+	retryInterval   time.Duration
+	retryBackoff    int
+	trace           bool
+	drainTimeout    time.Duration
+	signer          goEthTypes.Signer // Recovers transaction senders for the chain
+	id              uint64            // Chain ID, the one of the node when none is configured
+	chainID         string            // Label of the chain in metrics
+	head            atomic.Uint64     // Latest chain head, zero until it is known
+}
+
+// newPipeline sets up the RPC client, contract ABIs and settings for indexing a chain, with bds
+// limited to the chain. The block manager is left for the caller to set.
+func newPipeline(ctx context.Context, chainConfig coreData.Chain, bds *data.BlockchainDataStore, indexerConfig config.Indexer, contracts []config.Contract) (*pipeline, error) {
+	log.Println("Setup RPC client")
+	rpcClient, err := NewRPCClient(ctx, chainConfig.RPCs, indexerConfig.MaxRetries, indexerConfig.MaxBatchItems)
+	if err != nil {
+		log.Printf("Failed to create RPC client: %v", err)
+		return nil, fmt.Errorf("failed to create RPC client: %v", err)
+	}
+
+	// Records are keyed by the chain ID of the node, which must match the configured one. Without
+	// a configured chain ID the one of the node is used, never 0.
+	chainID, err := rpcClient.GetChainIDWithRetry(ctx)
+	if err != nil {
+		log.Printf("Failed to get chain ID: %v", err)
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+	if !chainID.IsUint64() || chainID.Sign() == 0 {
+		log.Printf("Invalid chain ID %s of the node", chainID)
+		return nil, fmt.Errorf("invalid chain ID %s of the node", chainID)
+	}
+	if chainConfig.ID == 0 {
+		log.Printf("No chain ID configured, using chain ID %s of the node", chainID)
+	} else if chainID.Cmp(big.NewInt(int64(chainConfig.ID))) != 0 {
+		log.Printf("Chain ID %d of %s does not match chain ID %s of the node", chainConfig.ID, chainConfig.Name, chainID)
+		return nil, fmt.Errorf("chain ID %d of %s does not match chain ID %s of the node", chainConfig.ID, chainConfig.Name, chainID)
+	}
+
+	log.Println("Load contract ABIs")
+	registry, err := NewABIRegistry(chainContracts(contracts, int(chainID.Int64())))
+	if err != nil {
+		log.Printf("Failed to load contract ABIs: %v", err)
+		return nil, fmt.Errorf("failed to load contract ABIs: %v", err)
+	}
+
+	p := &pipeline{
+		rpcClient:       rpcClient,
+		bds:             bds.ForChain(chainID.Uint64()),
+		registry:        registry,
+		pollInterval:    time.Duration(indexerConfig.PollInterval) * time.Second,
+		maxReorgDepth:   indexerConfig.MaxReorgDepth,
+		batchSize:       indexerConfig.BatchSize,
+		maxBlockRetries: indexerConfig.MaxBlockRetries,
+		retryInterval:   time.Duration(indexerConfig.RetryInterval) * time.Second,
+		retryBackoff:    indexerConfig.RetryBackoff,
+		trace:           indexerConfig.TraceInternalTransactions,
+		drainTimeout:    time.Duration(indexerConfig.DrainTimeout) * time.Second,
+		signer:          goEthTypes.LatestSignerForChainID(chainID),
+		id:              chainID.Uint64(),
+		chainID:         chainID.String(),
+	}
+	if p.pollInterval <= 0 {
+		p.pollInterval = defaultPollInterval
+	}
+	if p.maxReorgDepth <= 0 {
+		p.maxReorgDepth = defaultMaxReorgDepth
+	}
+	if p.batchSize <= 0 {
+		p.batchSize = 1
+	}
+	if p.maxBlockRetries < 0 {
+		p.maxBlockRetries = 0
+	} else if p.maxBlockRetries == 0 {
+		p.maxBlockRetries = defaultMaxBlockRetries
+	}
+	if p.retryInterval <= 0 {
+		p.retryInterval = defaultRetryInterval
+	}
+	if p.retryBackoff <= 0 {
+		p.retryBackoff = defaultRetryBackoff
+	}
+	if p.drainTimeout <= 0 {
+		p.drainTimeout = defaultDrainTimeout
+	}
+	return p, nil
+}
+
+// chainContracts returns the contracts deployed on a chain, contracts without a chain are on every chain
+func chainContracts(contracts []config.Contract, chainID int) []config.Contract {
+	result := make([]config.Contract, 0, len(contracts))
+	for _, contract := range contracts {
+		if contract.ChainID == 0 || contract.ChainID == chainID {
+			result = append(result, contract)
+		}
+	}
+	return result
+}
+
+// run distributes the blocks of the block manager across workers and waits for them to finish.
+// Once ctx is done the workers stop taking blocks, and the blocks in flight get the drain timeout
+// to finish before their RPC calls and writes are cancelled.
+func (p *pipeline) run(ctx context.Context, numWorkers int) {
+	if numWorkers <= 0 {
+		numWorkers = 1
+	}
+	workers.WithLabelValues(p.chainID).Add(float64(numWorkers))
+	defer workers.WithLabelValues(p.chainID).Sub(float64(numWorkers))
+
+	work, cancel := drainContext(ctx, p.drainTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go p.worker(ctx, work, i, &wg)
+	}
+	wg.Wait()
+}
+
+// drainContext returns the context of the work in flight, cancelled once timeout has passed
+// after ctx is done
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	work, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		log.Printf("Draining blocks in flight for up to %s", timeout)
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			log.Printf("Drain timeout expired, cancelling blocks in flight")
+			cancel()
+		case <-work.Done():
+		}
+	})
+	return work, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Worker function for goroutines to index ranges of blocks until ctx is done. Blocks are indexed
+// with the work context so that the ones in flight can finish after ctx.
+func (p *pipeline) worker(ctx, work context.Context, id int, wg *sync.WaitGroup) {
 	defer wg.Done()
 	log.Printf("Worker %d: Starting", id)
 	for {
-		blockNumber, ok := bm.GetNextBlock()
-		if !ok {
-			log.Printf("Worker %d: No more blocks to process", id)
+		if ctx.Err() != nil {
+			log.Printf("Worker %d: Stopping", id)
 			return
 		}
-		log.Printf("Worker %d: Indexing block %d", id, blockNumber)
-		err := indexBlock(rpcClient, blockNumber, bds)
+		blockNumbers, ok := p.bm.GetNextBlocks(p.batchSize)
+		p.observeQueue()
+		if !ok {
+			if p.bm.Done() {
+				log.Printf("Worker %d: No more blocks to process", id)
+				return
+			}
+			// Caught up with the chain head or waiting for failed blocks to be retried
+			sleep(ctx, p.pollInterval)
+			continue
+		}
+		log.Printf("Worker %d: Indexing blocks %d to %d", id, blockNumbers[0], blockNumbers[len(blockNumbers)-1])
+		busyWorkers.WithLabelValues(p.chainID).Inc()
+		failed := p.indexBlocks(work, blockNumbers)
+		done := make([]int, 0, len(blockNumbers))
+		for _, blockNumber := range blockNumbers {
+			if err, ok := failed[blockNumber]; ok {
+				if work.Err() != nil {
+					// Cancelled by the shutdown, the block is found missing on restart
+					log.Printf("Worker %d: Block %d cancelled: %v", id, blockNumber, err)
+					continue
+				}
+				log.Printf("Worker %d: Error indexing block %d: %v", id, blockNumber, err)
+				p.handleFailedBlock(work, blockNumber, err)
+			} else {
+				p.bm.ClearFailures(blockNumber)
+				done = append(done, blockNumber)
+				log.Printf("Worker %d: Successfully indexed block %d", id, blockNumber)
+			}
+		}
+		p.checkpoint.markDone(done)
+		p.checkpoint.save(work)
+		busyWorkers.WithLabelValues(p.chainID).Dec()
+		p.observeLag()
+	}
+}
+
+// observeQueue records the number of blocks waiting in the block manager
+func (p *pipeline) observeQueue() {
+	pending, missed, retry := p.bm.QueueDepth()
+	queueDepth.WithLabelValues(p.chainID, "pending").Set(float64(pending))
+	queueDepth.WithLabelValues(p.chainID, "missed").Set(float64(missed))
+	queueDepth.WithLabelValues(p.chainID, "retry").Set(float64(retry))
+}
+
+// setHead records the latest chain head reported by the node
+func (p *pipeline) setHead(head uint64) {
+	p.head.Store(head)
+	chainHead.WithLabelValues(p.chainID).Set(float64(head))
+	p.observeLag()
+}
+
+// observeLag records how far the indexed blocks are behind the chain head, once it is known
+func (p *pipeline) observeLag() {
+	if lag, ok := p.lag(); ok {
+		headLag.WithLabelValues(p.chainID).Set(float64(lag))
+	}
+}
+
+// lag returns the number of blocks between the chain head and the last contiguously indexed
+// block, false until the chain head is known
+func (p *pipeline) lag() (uint64, bool) {
+	head := p.head.Load()
+	if head == 0 {
+		return 0, false
+	}
+	// Every block below the low-water mark is indexed
+	if low := p.checkpoint.lowWaterMark(); head >= low {
+		return head - low + 1, true
+	}
+	return 0, true
+}
+
+// addHealthChecks makes readiness depend on the RPC of the chain and, with maxLag set, on the
+// indexer keeping up with the chain head
+func (p *pipeline) addHealthChecks(health *ginhelper.HealthChecker, maxLag int) {
+	health.AddReadinessCheck("rpc:"+p.chainID, p.rpcClient.Ping)
+	if maxLag <= 0 {
+		return
+	}
+	health.AddReadinessCheck("lag:"+p.chainID, func(ctx context.Context) error {
+		if lag, ok := p.lag(); ok && lag > uint64(maxLag) {
+			return fmt.Errorf("%d blocks behind the chain head, more than %d", lag, maxLag)
+		}
+		return nil
+	})
+}
+
+// handleFailedBlock re-queues a failed block with exponential backoff. Once the retry
+// budget is exhausted the block is recorded as failed so it can be retried later.
+func (p *pipeline) handleFailedBlock(ctx context.Context, blockNumber int, err error) {
+	blockFailures.WithLabelValues(p.chainID).Inc()
+	attempts := p.bm.RecordFailure(blockNumber)
+	if attempts <= p.maxBlockRetries {
+		delay := p.retryDelay(attempts)
+		log.Printf("Retrying block %d in %s (attempt %d of %d)", blockNumber, delay, attempts, p.maxBlockRetries)
+		p.bm.RetryBlock(blockNumber, delay)
+		return
+	}
+
+	p.bm.ClearFailures(blockNumber)
+	blocksFailed.WithLabelValues(p.chainID).Inc()
+	log.Printf("Giving up on block %d after %d attempts: %v", blockNumber, attempts, err)
+	failed := &data.FailedBlock{
+		Number:   uint64(blockNumber),
+		Error:    err.Error(),
+		Attempts: attempts,
+		FailedAt: time.Now(),
+	}
+	if err := p.bds.WithContext(ctx).SaveFailedBlock(failed); err != nil {
+		log.Printf("Failed to record failed block %d: %v", blockNumber, err)
+		return
+	}
+	// Failed blocks are retried with the retry-failed command, they no longer hold back the low-water mark
+	p.checkpoint.markDone([]int{blockNumber})
+}
+
+// retryDelay returns the delay before the given retry attempt of a failed block
+func (p *pipeline) retryDelay(attempt int) time.Duration {
+	delay := p.retryInterval
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= time.Duration(p.retryBackoff)
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// indexBlocks retrieves a range of blocks, their receipts and account balances with batched RPC
+// calls and indexes the blocks in order. It returns the errors of the blocks that failed.
+func (p *pipeline) indexBlocks(ctx context.Context, blockNumbers []int) map[int]error {
+	failed := make(map[int]error)
+	failAll := func(err error) map[int]error {
+		for _, blockNumber := range blockNumbers {
+			failed[blockNumber] = err
+		}
+		return failed
+	}
+
+	numbers := make([]uint64, 0, len(blockNumbers))
+	for _, blockNumber := range blockNumbers {
+		numbers = append(numbers, uint64(blockNumber))
+	}
+	blocks, err := p.rpcClient.GetBlocksWithRetry(ctx, numbers)
+	if err != nil {
+		log.Printf("Error retrieving blocks %d to %d: %v", blockNumbers[0], blockNumbers[len(blockNumbers)-1], err)
+		return failAll(err)
+	}
+
+	receipts, err := p.rpcClient.GetBlocksReceiptsWithRetry(ctx, blocks)
+	if err != nil {
+		log.Printf("Error retrieving receipts for blocks %d to %d: %v", blockNumbers[0], blockNumbers[len(blockNumbers)-1], err)
+		return failAll(err)
+	}
+
+	traces := make([][]TxTrace, len(blocks))
+	if p.trace {
+		for i, block := range blocks {
+			traces[i], err = p.rpcClient.TraceBlockWithRetry(ctx, block.NumberU64())
+			if err != nil {
+				log.Printf("Error tracing block %d: %v", blockNumbers[i], err)
+				failed[blockNumbers[i]] = err
+			}
+		}
+	}
+
+	accounts := make([][]goEthCommon.Address, len(blocks))
+	for i, block := range blocks {
+		accounts[i], err = processAccounts(evm.GetTransactions(block), p.signer)
 		if err != nil {
-			log.Printf("Worker %d: Error indexing block %d: %v", id, blockNumber, err)
-			// bm.AddMissedBlock(blockNumber) // TODO - Add back missed block
-		} else {
-			log.Printf("Worker %d: Successfully indexed block %d", id, blockNumber)
+			log.Printf("Error processing accounts for block %d: %v", blockNumbers[i], err)
+			failed[blockNumbers[i]] = err
+		}
+	}
+
+	accountsWithBalance, err := retrieveAccountsWithBalance(ctx, p.rpcClient, blocks, accounts)
+	if err != nil {
+		log.Printf("Error retrieving accounts with balance for blocks %d to %d: %v", blockNumbers[0], blockNumbers[len(blockNumbers)-1], err)
+		return failAll(err)
+	}
+
+	for i, block := range blocks {
+		if _, ok := failed[blockNumbers[i]]; ok {
+			continue
+		}
+		if err := p.indexBlock(ctx, block, receipts[i], traces[i], accountsWithBalance[i]); err != nil {
+			failed[blockNumbers[i]] = err
 		}
 	}
+	return failed
 }
 
-// indexBlock retrieves and processes a block
-func indexBlock(rpcClient *RPCClient, blockNumber int, bds *data.BlockchainDataStore) error {
+// indexBlock processes a retrieved block and saves it
+func (p *pipeline) indexBlock(ctx context.Context, block *goEthTypes.Block, goEthReceipts []*goEthTypes.Receipt, traces []TxTrace, accountsWithBalance []*data.Account) error {
+	blockNumber := block.NumberU64()
 	log.Printf("Indexing block %d", blockNumber)
-	block, err := rpcClient.GetBlockWithRetry(uint64(blockNumber))
+	bds := p.bds.WithContext(ctx)
+
+	orphaned, err := detectReorg(ctx, p.rpcClient, bds, block, p.maxReorgDepth)
+	if err != nil {
+		log.Printf("Error checking block %d for reorg: %v", blockNumber, err)
+		return err
+	}
+	child, err := detectOrphanedChild(bds, block)
 	if err != nil {
-		log.Printf("Error retrieving block %d: %v", blockNumber, err)
+		log.Printf("Error checking block %d for reorg: %v", blockNumber, err)
 		return err
 	}
+	if child != nil {
+		orphaned = append([]*data.Block{child}, orphaned...)
+	}
+	if len(orphaned) > 0 {
+		if err := handleReorg(p.bm, p.checkpoint, bds, block, orphaned); err != nil {
+			log.Printf("Error handling reorg at block %d: %v", blockNumber, err)
+			return err
+		}
+	}
 
 	goEthTxs := evm.GetTransactions(block)
-	transactions, err := processTransactions(block, goEthTxs)
+	transactions, err := processTransactions(block, goEthTxs, p.signer)
 	if err != nil {
 		log.Printf("Error processing transactions for block %d: %v", blockNumber, err)
 		return err
 	}
 
-	accounts, err := processAccounts(goEthTxs)
+	receipts, logs := processReceipts(goEthReceipts)
+	internalTxs, deployments, err := processInternalTransactions(block, traces)
 	if err != nil {
-		log.Printf("Error processing accounts for block %d: %v", blockNumber, err)
+		log.Printf("Error processing internal transactions for block %d: %v", blockNumber, err)
 		return err
 	}
-
-	accountsWithBalance, err := retrieveAccountsWithBalance(rpcClient, accounts)
+	contracts, err := processContracts(ctx, p.rpcClient, block, transactions, goEthReceipts, deployments)
 	if err != nil {
-		log.Printf("Error retrieving accounts with balance for block %d: %v", blockNumber, err)
+		log.Printf("Error processing contracts for block %d: %v", blockNumber, err)
 		return err
 	}
+	balances := processBalances(block, accountsWithBalance)
+	tokenTransfers := processTokenTransfers(block, goEthReceipts)
+	decodedEvents := processDecodedEvents(p.registry, block, goEthReceipts)
 
 	blockData := data.CreateBlockData(block)
 
-	err = bds.SaveBlock(blockData, transactions, accountsWithBalance)
+	start := time.Now()
+	err = bds.SaveBlock(&data.IndexedBlock{
+		Block:                blockData,
+		Transactions:         transactions,
+		Accounts:             accountsWithBalance,
+		Balances:             balances,
+		Receipts:             receipts,
+		Logs:                 logs,
+		InternalTransactions: internalTxs,
+		Contracts:            contracts,
+		TokenTransfers:       tokenTransfers,
+		DecodedEvents:        decodedEvents,
+	})
+	dbWriteDuration.WithLabelValues(p.chainID).Observe(time.Since(start).Seconds())
 	if err != nil {
 		log.Printf("Failed to save block %d: %v", blockNumber, err)
 		return fmt.Errorf("failed to save block %d: %v", blockNumber, err)
 	}
+	blocksIndexed.WithLabelValues(p.chainID).Inc()
+	p.bds.Events().Publish(&data.BlockEvent{Block: blockData, Transactions: transactions})
 
 	log.Printf("Successfully indexed block %d", blockNumber)
 	return nil
 }
 
 // processTransactions processes transactions within a block
-func processTransactions(block *goEthTypes.Block, txs []*goEthTypes.Transaction) ([]*data.Transaction, error) {
+func processTransactions(block *goEthTypes.Block, txs []*goEthTypes.Transaction, signer goEthTypes.Signer) ([]*data.Transaction, error) {
 	log.Printf("Processing transactions for block %d", block.Number().Uint64())
 	var transactions []*data.Transaction = make([]*data.Transaction, 0)
 
-	for _, tx := range txs {
-		txData, err := data.CreateTransactionData(tx, block)
+	for i, tx := range txs {
+		txData, err := data.CreateTransactionData(tx, block, i, signer)
 		if err != nil {
 			log.Printf("Failed to create transaction %s: %v", tx.Hash().Hex(), err)
 			return nil, fmt.Errorf("failed to create transaction %s: %v", tx.Hash().Hex(), err)
@@ -90,12 +474,34 @@ func processTransactions(block *goEthTypes.Block, txs []*goEthTypes.Transaction)
 	return transactions, nil
 }
 
+// processReceipts processes the receipts of a block and the logs they contain
+func processReceipts(goEthReceipts []*goEthTypes.Receipt) ([]*data.Receipt, []*data.Log) {
+	receipts := make([]*data.Receipt, 0, len(goEthReceipts))
+	logs := make([]*data.Log, 0)
+	for _, receipt := range goEthReceipts {
+		receipts = append(receipts, data.CreateReceiptData(receipt))
+		for _, l := range receipt.Logs {
+			logs = append(logs, data.CreateLogData(l))
+		}
+	}
+	return receipts, logs
+}
+
+// processBalances records the balances of the accounts involved in a block
+func processBalances(block *goEthTypes.Block, accounts []*data.Account) []*data.AccountBalance {
+	balances := make([]*data.AccountBalance, 0, len(accounts))
+	for _, account := range accounts {
+		balances = append(balances, data.CreateAccountBalanceData(account, block))
+	}
+	return balances
+}
+
 // processAccounts processes accounts involved in a transaction
-func processAccounts(txs []*goEthTypes.Transaction) ([]goEthCommon.Address, error) {
+func processAccounts(txs []*goEthTypes.Transaction, signer goEthTypes.Signer) ([]goEthCommon.Address, error) {
 	accounts := make(map[string]goEthCommon.Address)
 	for _, tx := range txs {
-		// Assuming From() method returns the sender's address (common.Address)
-		from, err := goEthTypes.Sender(goEthTypes.LatestSignerForChainID(tx.ChainId()), tx)
+		// The sender is cached in the transaction, so it is recovered once for the transaction data too
+		from, err := goEthTypes.Sender(signer, tx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get sender address: %w", err)
 		}
@@ -116,65 +522,186 @@ func processAccounts(txs []*goEthTypes.Transaction) ([]goEthCommon.Address, erro
 	return uniqueAddresses, nil
 }
 
-func retrieveAccountsWithBalance(rpcClient *RPCClient, accounts []goEthCommon.Address) ([]*data.Account, error) {
-	accountsWithBalance := make([]*data.Account, 0, len(accounts))
+// retrieveAccountsWithBalance retrieves the balances of the accounts of several blocks in a single batch.
+// Balances are retrieved at the block involving the account.
+func retrieveAccountsWithBalance(ctx context.Context, rpcClient *RPCClient, blocks []*goEthTypes.Block, accounts [][]goEthCommon.Address) ([][]*data.Account, error) {
+	queries := make([]BalanceQuery, 0)
+	for i, blockAccounts := range accounts {
+		for _, account := range blockAccounts {
+			queries = append(queries, BalanceQuery{Account: account, BlockNumber: blocks[i].Number()})
+		}
+	}
 
-	for _, account := range accounts {
-		balance, err := rpcClient.GetBalanceWithRetry(account)
+	balances, err := rpcClient.GetBalancesWithRetry(ctx, queries)
+	if err != nil {
+		log.Printf("Failed to retrieve account balances: %v", err)
+		return nil, fmt.Errorf("failed to retrieve account balances: %v", err)
+	}
+
+	accountsWithBalance := make([][]*data.Account, len(accounts))
+	next := 0
+	for i, blockAccounts := range accounts {
+		accountsWithBalance[i] = make([]*data.Account, 0, len(blockAccounts))
+		for _, account := range blockAccounts {
+			accountsWithBalance[i] = append(accountsWithBalance[i], data.CreateAccountData(account, balances[next], blocks[i].NumberU64()))
+			next++
+		}
+	}
+
+	return accountsWithBalance, nil
+}
+
+// safeHead returns the highest block with enough confirmations
+func safeHead(head uint64, confirmations int) int {
+	if uint64(confirmations) > head {
+		return 0
+	}
+	return int(head) - confirmations
+}
+
+// followHead polls the chain head and extends the block manager range as it advances, until ctx
+// is done
+func (p *pipeline) followHead(ctx context.Context, confirmations int) {
+	for sleep(ctx, p.pollInterval) == nil {
+		head, err := p.rpcClient.GetLatestBlockNumberWithRetry(ctx)
 		if err != nil {
-			log.Printf("Failed to retrieve account balance for %s: %v", account.Hex(), err)
-			return nil, fmt.Errorf("failed to retrieve account balance for %s: %v", account.Hex(), err)
+			log.Printf("Failed to get latest block number: %v", err)
+			continue
 		}
-		accountData := data.CreateAccountData(account, balance)
+		p.setHead(head)
+		p.bm.SetMaxBlock(safeHead(head, confirmations))
+	}
+}
 
-		accountsWithBalance = append(accountsWithBalance, accountData)
+// StartIndexingChains indexes several chains concurrently, with one pipeline per chain. The
+// pipelines add their readiness checks to health when it is set. It returns once every chain
+// is indexed or, after ctx is done, once the blocks in flight are drained.
+func StartIndexingChains(ctx context.Context, chains []coreData.Chain, bds *data.BlockchainDataStore, indexerConfig config.Indexer, contracts []config.Contract, health *ginhelper.HealthChecker) error {
+	if len(chains) == 0 {
+		return fmt.Errorf("no chains configured")
 	}
 
-	return accountsWithBalance, nil
+	var wg sync.WaitGroup
+	errs := make([]error, len(chains))
+	for i, chain := range chains {
+		wg.Add(1)
+		go func(i int, chain coreData.Chain) {
+			defer wg.Done()
+			errs[i] = StartIndexing(ctx, chain, bds, indexerConfig, contracts, health)
+			if errs[i] != nil {
+				log.Printf("Indexing chain %s (%d) failed: %v", chain.Name, chain.ID, errs[i])
+			}
+		}(i, chain)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
 }
 
-// StartIndexing initializes the process
-func StartIndexing(chainConfig coreData.Chain, bds *data.BlockchainDataStore, indexerConfig config.Indexer) error {
-	log.Println("## Starting indexing process...")
-	log.Println("Setup RPC client")
-	rpcClient, err := NewRPCClient(chainConfig.RPCs, indexerConfig.MaxRetries)
+// StartIndexing initializes the process for a single chain
+func StartIndexing(ctx context.Context, chainConfig coreData.Chain, bds *data.BlockchainDataStore, indexerConfig config.Indexer, contracts []config.Contract, health *ginhelper.HealthChecker) error {
+	log.Printf("## Starting indexing process for chain %s (%d)...", chainConfig.Name, chainConfig.ID)
+	p, err := newPipeline(ctx, chainConfig, bds, indexerConfig, contracts)
 	if err != nil {
-		log.Printf("Failed to create RPC client: %v", err)
-		return fmt.Errorf("failed to create RPC client: %v", err)
+		return err
 	}
+	rpcClient := p.rpcClient
+	bds = p.bds
 
-	// Get the latest saved block from the data store
-	latestSavedBlock, err := bds.GetLatestSavedBlock()
-	if err != nil {
-		log.Printf("Failed to get latest saved block: %v", err)
-		return fmt.Errorf("failed to get latest saved block: %v", err)
+	// Resolve the last block to index, following the chain head if no end block is set
+	follow := indexerConfig.FollowHead()
+	var endBlock int
+	var head uint64
+	if follow {
+		head, err = rpcClient.GetLatestBlockNumberWithRetry(ctx)
+		if err != nil {
+			log.Printf("Failed to get latest block number: %v", err)
+			return fmt.Errorf("failed to get latest block number: %v", err)
+		}
+		endBlock = safeHead(head, indexerConfig.Confirmations)
+		log.Printf("Following chain head %d with %d confirmations", head, indexerConfig.Confirmations)
+	} else {
+		endBlock, err = indexerConfig.EndBlockNumber()
+		if err != nil {
+			log.Printf("Invalid end block %q: %v", indexerConfig.EndBlock, err)
+			return fmt.Errorf("invalid end block %q: %v", indexerConfig.EndBlock, err)
+		}
 	}
-	log.Printf("Latest saved block: %d", latestSavedBlock)
 
-	// Ensure that the latest saved block is within the range of start and end blocks
-	if latestSavedBlock <= uint64(indexerConfig.StartBlock) {
-		latestSavedBlock = uint64(indexerConfig.StartBlock)
-	} else if latestSavedBlock > uint64(indexerConfig.EndBlock) {
-		latestSavedBlock = uint64(indexerConfig.EndBlock)
+	// Resume from the persisted water marks instead of scanning every saved block
+	cp, err := loadCheckpoint(bds.WithContext(ctx), p.id, uint64(indexerConfig.StartBlock))
+	if err != nil {
+		log.Printf("Failed to load indexer state: %v", err)
+		return fmt.Errorf("failed to load indexer state: %v", err)
 	}
-	log.Printf("Starting from block %d", latestSavedBlock)
+	log.Printf("Indexer state: low-water mark %d, high-water mark %d", cp.state.LowWaterMark, cp.state.HighWaterMark)
 
-	// Identify missing blocks from startBlock to latestSavedBlock
-	log.Println("Identify missing blocks")
-	missedBlocks := bds.IdentifyMissingBlocks(uint64(indexerConfig.StartBlock), latestSavedBlock)
+	// Only blocks between the water marks can be missing, and none beyond the end block
+	missedBlocks, nextBlock, err := cp.resume(uint64(endBlock))
+	if err != nil {
+		log.Printf("Failed to identify missing blocks: %v", err)
+		return fmt.Errorf("failed to identify missing blocks: %v", err)
+	}
+	log.Printf("Starting from block %d, low-water mark %d", nextBlock, cp.lowWaterMark())
 
-	// Create BlockManager with missing blocks from start to latest saved block
-	blockManager := NewBlockManager(int(latestSavedBlock), indexerConfig.EndBlock)
+	// Create BlockManager with the missing blocks between the water marks
+	blockManager := NewBlockManager(int(nextBlock), endBlock, follow)
 	blockManager.AddMissedBlocks(missedBlocks)
 
+	p.bm = blockManager
+	p.checkpoint = cp
+	if health != nil {
+		p.addHealthChecks(health, indexerConfig.MaxLag)
+	}
+	if follow {
+		p.setHead(head)
+		go p.followHead(ctx, indexerConfig.Confirmations)
+	}
+
 	// Distribute the load across multiple goroutines
-	var wg sync.WaitGroup
-	numWorkers := indexerConfig.MaxWorkers
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go worker(i, blockManager, rpcClient, bds, &wg)
+	p.run(ctx, indexerConfig.MaxWorkers)
+	if ctx.Err() != nil {
+		log.Printf("Indexing process stopped for chain %s (%d)", chainConfig.Name, p.id)
+		return nil
 	}
-	wg.Wait()
-	log.Println("Indexing process completed")
+	log.Printf("Indexing process completed for chain %s (%d)", chainConfig.Name, p.id)
+	return nil
+}
+
+// RetryFailedBlocks indexes the blocks recorded as failed again. Blocks that are indexed
+// successfully are removed from the failed blocks.
+func RetryFailedBlocks(ctx context.Context, chainConfig coreData.Chain, bds *data.BlockchainDataStore, indexerConfig config.Indexer, contracts []config.Contract) error {
+	log.Printf("## Retrying failed blocks of chain %s (%d)...", chainConfig.Name, chainConfig.ID)
+	p, err := newPipeline(ctx, chainConfig, bds, indexerConfig, contracts)
+	if err != nil {
+		return err
+	}
+	bds = p.bds
+	failedBlocks, err := bds.WithContext(ctx).GetFailedBlocks()
+	if err != nil {
+		log.Printf("Failed to get failed blocks: %v", err)
+		return fmt.Errorf("failed to get failed blocks: %v", err)
+	}
+	if len(failedBlocks) == 0 {
+		log.Println("No failed blocks to retry")
+		return nil
+	}
+
+	p.checkpoint, err = loadCheckpoint(bds.WithContext(ctx), p.id, uint64(indexerConfig.StartBlock))
+	if err != nil {
+		log.Printf("Failed to load indexer state: %v", err)
+		return fmt.Errorf("failed to load indexer state: %v", err)
+	}
+
+	blockNumbers := make([]int, 0, len(failedBlocks))
+	for _, failed := range failedBlocks {
+		blockNumbers = append(blockNumbers, int(failed.Number))
+	}
+	log.Printf("Retrying %d failed blocks", len(blockNumbers))
+
+	// Only hand out the failed blocks: the regular range is already exhausted
+	p.bm = NewBlockManager(1, 0, false)
+	p.bm.AddMissedBlocks(blockNumbers)
+	p.run(ctx, indexerConfig.MaxWorkers)
+	log.Println("Retrying failed blocks completed")
 	return nil
 }
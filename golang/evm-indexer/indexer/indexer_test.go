@@ -0,0 +1,151 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// newDrainPipeline returns a pipeline indexing block 10, whose retrieval is held by the node until
+// rpc.eth.release is closed
+func newDrainPipeline(t *testing.T, drainTimeout time.Duration) (*pipeline, *fakeRPC) {
+	t.Helper()
+	bds := newTestStore(t)
+	rpc := newFakeRPC(t, 0)
+	client := newFakeRPCClient(t, rpc, 0)
+	rpc.eth.byNumber[10] = canonicalHeaders(10, 10)[0]
+	rpc.eth.held = make(chan uint64, 1)
+	rpc.eth.release = make(chan struct{})
+	// Held requests must end before the server closes
+	t.Cleanup(func() {
+		select {
+		case <-rpc.eth.release:
+		default:
+			close(rpc.eth.release)
+		}
+	})
+
+	cp, err := loadCheckpoint(bds, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pipeline{
+		bm:              NewBlockManager(10, 10, false),
+		checkpoint:      cp,
+		rpcClient:       client,
+		bds:             bds,
+		registry:        &ABIRegistry{},
+		pollInterval:    10 * time.Millisecond,
+		maxReorgDepth:   defaultMaxReorgDepth,
+		batchSize:       1,
+		maxBlockRetries: defaultMaxBlockRetries,
+		retryInterval:   time.Second,
+		retryBackoff:    defaultRetryBackoff,
+		drainTimeout:    drainTimeout,
+		signer:          goEthTypes.LatestSignerForChainID(big.NewInt(1)),
+		id:              1,
+		chainID:         "1",
+	}, rpc
+}
+
+// runUntilHeld runs the pipeline until block 10 is in flight, then cancels its context and returns
+// the channel closed once the pipeline stops
+func runUntilHeld(t *testing.T, p *pipeline, rpc *fakeRPC) <-chan struct{} {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		p.run(ctx, 1)
+	}()
+
+	select {
+	case <-rpc.eth.held:
+	case <-time.After(5 * time.Second):
+		t.Fatal("block 10 never requested")
+	}
+	cancel()
+	return stopped
+}
+
+func TestInFlightBlocksAreSavedWithinDrainTimeout(t *testing.T) {
+	p, rpc := newDrainPipeline(t, 5*time.Second)
+	stopped := runUntilHeld(t, p, rpc)
+
+	// The node answers after the shutdown started, within the drain timeout
+	time.Sleep(50 * time.Millisecond)
+	close(rpc.eth.release)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline still running after the block in flight was indexed")
+	}
+
+	block, err := p.bds.GetBlockByNumber(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block == nil || block.Hash != rpc.eth.byNumber[10].Hash().Hex() {
+		t.Fatalf("block 10 = %+v after the drain, want it saved", block)
+	}
+	if low := p.checkpoint.lowWaterMark(); low != 11 {
+		t.Errorf("low-water mark = %d, want 11", low)
+	}
+	state, err := p.bds.GetIndexerState(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil || state.LowWaterMark != 11 {
+		t.Errorf("saved indexer state = %+v, want low-water mark 11", state)
+	}
+}
+
+func TestInFlightBlocksAreCancelledAfterDrainTimeout(t *testing.T) {
+	p, rpc := newDrainPipeline(t, 50*time.Millisecond)
+	stopped := runUntilHeld(t, p, rpc)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline still running after the drain timeout")
+	}
+
+	block, err := p.bds.GetBlockByNumber(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block != nil {
+		t.Errorf("block 10 saved after the drain timeout")
+	}
+	// The cancelled block is found as a gap on restart rather than recorded as failed
+	failed, err := p.bds.GetFailedBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 0 {
+		t.Errorf("failed blocks %v, want none", failed)
+	}
+	if low := p.checkpoint.lowWaterMark(); low != 10 {
+		t.Errorf("low-water mark = %d, want 10", low)
+	}
+}
+
+func TestDrainContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	work, stop := drainContext(ctx, 50*time.Millisecond)
+	defer stop()
+
+	cancel()
+	if work.Err() != nil {
+		t.Fatal("work cancelled with its parent, want it to drain")
+	}
+	select {
+	case <-work.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("work not cancelled after the drain timeout")
+	}
+}
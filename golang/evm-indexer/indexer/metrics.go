@@ -0,0 +1,104 @@
+package indexer
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	ethRpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricsNamespace prefixes the name of every indexer metric
+const metricsNamespace = "evm_indexer"
+
+// Collectors of the indexing pipeline, served by /metrics with the default registry
+var (
+	blocksIndexed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocks_indexed_total",
+		Help:      "Blocks indexed and saved.",
+	}, []string{"chain_id"})
+	blockFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "block_failures_total",
+		Help:      "Failed attempts to index a block, including the ones retried later.",
+	}, []string{"chain_id"})
+	blocksFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocks_failed_total",
+		Help:      "Blocks given up on after exhausting their retries and recorded as failed.",
+	}, []string{"chain_id"})
+	rpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rpc_requests_total",
+		Help:      "JSON-RPC requests by method, endpoint host and status, a batch counting as one request.",
+	}, []string{"method", "endpoint", "status"})
+	rpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Latency of JSON-RPC requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "endpoint"})
+	dbWriteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_write_duration_seconds",
+		Help:      "Time to save an indexed block and its records.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"chain_id"})
+	workers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "workers",
+		Help:      "Running workers.",
+	}, []string{"chain_id"})
+	busyWorkers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "workers_busy",
+		Help:      "Workers indexing blocks, the others are waiting for blocks to index.",
+	}, []string{"chain_id"})
+	queueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "queue_depth",
+		Help:      "Blocks waiting to be handed out by the block manager: pending in the range, missed or waiting for a retry.",
+	}, []string{"chain_id", "queue"})
+	chainHead = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "chain_head_block",
+		Help:      "Latest block number reported by the node.",
+	}, []string{"chain_id"})
+	headLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "head_lag_blocks",
+		Help:      "Blocks between the chain head and the last contiguously indexed block.",
+	}, []string{"chain_id"})
+)
+
+// observeRPC records the outcome and latency of a JSON-RPC request sent at start
+func observeRPC(method, endpoint string, start time.Time, err error) {
+	rpcDuration.WithLabelValues(method, endpoint).Observe(time.Since(start).Seconds())
+	rpcRequests.WithLabelValues(method, endpoint, rpcStatus(err)).Inc()
+}
+
+// rpcStatus labels the outcome of a request: ok, the HTTP status code of a rejected request, or error
+func rpcStatus(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	var httpErr ethRpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return strconv.Itoa(httpErr.StatusCode)
+	}
+	return "error"
+}
+
+// endpointLabel returns the host of an RPC URL, leaving out paths and credentials that often
+// hold API keys
+func endpointLabel(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
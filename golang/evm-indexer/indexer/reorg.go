@@ -0,0 +1,81 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+const defaultMaxReorgDepth = 64
+
+// detectReorg verifies the parent hash of a new block against the stored block at number-1.
+// When they differ it walks back the canonical chain until it reaches the common ancestor and
+// returns the stored blocks that are no longer canonical, newest first. Reorgs replacing up to
+// maxDepth blocks are returned, deeper ones are an error.
+func detectReorg(ctx context.Context, rpcClient *RPCClient, bds *data.BlockchainDataStore, block *goEthTypes.Block, maxDepth int) ([]*data.Block, error) {
+	if block.NumberU64() == 0 {
+		return nil, nil
+	}
+
+	parentHash := block.ParentHash()
+	orphaned := []*data.Block{}
+	for number := block.NumberU64() - 1; ; number-- {
+		stored, err := bds.GetBlockByNumber(number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stored block %d: %v", number, err)
+		}
+		if stored == nil || stored.Hash == parentHash.Hex() {
+			// Reached the common ancestor, or a block that has not been indexed yet
+			return orphaned, nil
+		}
+
+		orphaned = append(orphaned, stored)
+		if len(orphaned) > maxDepth || number == 0 {
+			return nil, fmt.Errorf("reorg at block %d exceeds max depth %d", block.NumberU64(), maxDepth)
+		}
+
+		canonical, err := rpcClient.GetBlockByHashWithRetry(ctx, parentHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve canonical block %s: %v", parentHash.Hex(), err)
+		}
+		parentHash = canonical.ParentHash()
+	}
+}
+
+// detectOrphanedChild verifies the parent hash of the stored block at number+1 against a new block.
+// Workers index blocks concurrently, so a child can be saved before its parent: when it does not
+// build on the new block it is no longer canonical and is returned. Its own stored child is checked
+// in turn once it is indexed again.
+func detectOrphanedChild(bds *data.BlockchainDataStore, block *goEthTypes.Block) (*data.Block, error) {
+	number := block.NumberU64() + 1
+	stored, err := bds.GetBlockByNumber(number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stored block %d: %v", number, err)
+	}
+	if stored == nil || stored.ParentHash == block.Hash().Hex() {
+		return nil, nil
+	}
+	return stored, nil
+}
+
+// handleReorg rolls back the orphaned blocks and queues their numbers to re-index the canonical branch
+func handleReorg(bm *BlockManager, cp *checkpoint, bds *data.BlockchainDataStore, block *goEthTypes.Block, orphaned []*data.Block) error {
+	hashes := make([]string, 0, len(orphaned))
+	numbers := make([]int, 0, len(orphaned))
+	for _, b := range orphaned {
+		hashes = append(hashes, b.Hash)
+		numbers = append(numbers, int(b.Number))
+	}
+
+	log.Printf("Reorg detected at block %d: rolling back %d blocks %v", block.NumberU64(), len(orphaned), numbers)
+
+	if err := bds.RollbackBlocks(hashes); err != nil {
+		return fmt.Errorf("failed to roll back orphaned blocks: %v", err)
+	}
+	cp.rewind(numbers)
+	bm.AddMissedBlocks(numbers)
+	return nil
+}
@@ -0,0 +1,173 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	goEthCommon "github.com/ethereum/go-ethereum/common"
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+// holder is an account whose balance is recorded in every test block, as the block number
+const holder = "0x00000000000000000000000000000000000000A1"
+
+// canonicalHeaders returns headers of the blocks from..to, each the parent of the next one
+func canonicalHeaders(from, to uint64) []*goEthTypes.Header {
+	headers := make([]*goEthTypes.Header, 0, to-from+1)
+	var parent goEthCommon.Hash
+	for number := from; number <= to; number++ {
+		header := &goEthTypes.Header{
+			ParentHash:  parent,
+			UncleHash:   goEthTypes.EmptyUncleHash,
+			TxHash:      goEthTypes.EmptyTxsHash,
+			ReceiptHash: goEthTypes.EmptyReceiptsHash,
+			Difficulty:  big.NewInt(0),
+			Number:      new(big.Int).SetUint64(number),
+			Time:        1700000000 + number*12,
+		}
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	return headers
+}
+
+// saveLinkedBlock stores a block with its parent hash and the balance of holder at it
+func saveLinkedBlock(t *testing.T, bds *data.BlockchainDataStore, number uint64, hash, parentHash string) {
+	t.Helper()
+	balance := fmt.Sprint(number)
+	err := bds.SaveBlock(&data.IndexedBlock{
+		Block:    &data.Block{ID: hash, Hash: hash, ParentHash: parentHash, Number: number},
+		Accounts: []*data.Account{{Address: holder, Balance: balance, BalanceBlock: number}},
+		Balances: []*data.AccountBalance{{Address: holder, BlockNumber: number, BlockHash: hash, Balance: balance}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newReorg stores block 10 and a branch of depth blocks on top of it, and serves a canonical branch
+// of depth+1 blocks from block 10. It returns the head of the canonical branch, which builds on
+// the last replaced block.
+func newReorg(t *testing.T, depth int) (*data.BlockchainDataStore, *RPCClient, *goEthTypes.Block) {
+	t.Helper()
+	bds := newTestStore(t)
+	rpc := newFakeRPC(t, 0)
+	client := newFakeRPCClient(t, rpc, 0)
+
+	canonical := canonicalHeaders(10, uint64(11+depth))
+	for _, header := range canonical {
+		rpc.eth.blocks[header.Hash()] = header
+	}
+	parent := canonical[0].Hash().Hex()
+	saveLinkedBlock(t, bds, 10, parent, canonical[0].ParentHash.Hex())
+	for number := uint64(11); number <= uint64(10+depth); number++ {
+		hash := fmt.Sprintf("0x%064x", 0xdead00+number)
+		saveLinkedBlock(t, bds, number, hash, parent)
+		parent = hash
+	}
+	return bds, client, goEthTypes.NewBlockWithHeader(canonical[len(canonical)-1])
+}
+
+// blockNumbers returns the numbers of blocks in order
+func blockNumbers(blocks []*data.Block) []uint64 {
+	numbers := make([]uint64, len(blocks))
+	for i, block := range blocks {
+		numbers[i] = block.Number
+	}
+	return numbers
+}
+
+func TestReorgOfOneBlock(t *testing.T) {
+	bds, client, head := newReorg(t, 1)
+	cp, err := loadCheckpoint(bds, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cp.resume(100); err != nil {
+		t.Fatal(err)
+	}
+	bm := NewBlockManager(13, 13, false)
+
+	orphaned, err := detectReorg(context.Background(), client, bds, head, defaultMaxReorgDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numbers := blockNumbers(orphaned); !reflect.DeepEqual(numbers, []uint64{11}) {
+		t.Fatalf("orphaned blocks %v, want [11]", numbers)
+	}
+	if err := handleReorg(bm, cp, bds, head, orphaned); err != nil {
+		t.Fatal(err)
+	}
+
+	if block, err := bds.GetBlockByNumber(11); err != nil || block != nil {
+		t.Errorf("block 11 = %v, %v after the rollback, want none", block, err)
+	}
+	account, err := bds.GetAccountByAddress(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != "10" || account.BalanceBlock != 10 {
+		t.Errorf("balance %s at block %d after the rollback, want 10 at block 10", account.Balance, account.BalanceBlock)
+	}
+	if low := cp.lowWaterMark(); low != 11 {
+		t.Errorf("low-water mark = %d after the rollback, want 11", low)
+	}
+	if _, queued := bm.missedBlocks[11]; !queued {
+		t.Error("block 11 not queued to be indexed again")
+	}
+}
+
+func TestReorgAtMaxDepth(t *testing.T) {
+	bds, client, head := newReorg(t, 3)
+
+	orphaned, err := detectReorg(context.Background(), client, bds, head, 3)
+	if err != nil {
+		t.Fatalf("reorg of max depth 3 blocks: %v", err)
+	}
+	if numbers := blockNumbers(orphaned); !reflect.DeepEqual(numbers, []uint64{13, 12, 11}) {
+		t.Errorf("orphaned blocks %v, want [13 12 11]", numbers)
+	}
+
+	if _, err := detectReorg(context.Background(), client, bds, head, 2); err == nil {
+		t.Error("reorg of 3 blocks handled with a max depth of 2")
+	}
+}
+
+func TestReorgWithoutFork(t *testing.T) {
+	bds := newTestStore(t)
+	headers := canonicalHeaders(10, 11)
+	saveLinkedBlock(t, bds, 10, headers[0].Hash().Hex(), headers[0].ParentHash.Hex())
+
+	orphaned, err := detectReorg(context.Background(), nil, bds, goEthTypes.NewBlockWithHeader(headers[1]), defaultMaxReorgDepth)
+	if err != nil || len(orphaned) != 0 {
+		t.Errorf("orphaned blocks %v, %v on a block building on the stored one, want none", orphaned, err)
+	}
+}
+
+func TestOrphanedChild(t *testing.T) {
+	bds := newTestStore(t)
+	headers := canonicalHeaders(10, 12)
+	parent := goEthTypes.NewBlockWithHeader(headers[1])
+
+	// The worker indexing block 12 saved it from a branch that block 11 is not on
+	saveLinkedBlock(t, bds, 12, headers[2].Hash().Hex(), fmt.Sprintf("0x%064x", 0xdead11))
+	child, err := detectOrphanedChild(bds, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child == nil || child.Number != 12 {
+		t.Fatalf("orphaned child = %v, want block 12", child)
+	}
+
+	if err := bds.RollbackBlocks([]string{child.Hash}); err != nil {
+		t.Fatal(err)
+	}
+	saveLinkedBlock(t, bds, 12, headers[2].Hash().Hex(), headers[1].Hash().Hex())
+	if child, err := detectOrphanedChild(bds, parent); err != nil || child != nil {
+		t.Errorf("orphaned child = %v, %v for a child building on the block, want none", child, err)
+	}
+}
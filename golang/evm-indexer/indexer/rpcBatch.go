@@ -0,0 +1,377 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethRpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// BalanceQuery is an account balance to retrieve at a block, a nil block number means the latest block
+type BalanceQuery struct {
+	Account     common.Address
+	BlockNumber *big.Int
+}
+
+// rpcBlock is the part of an eth_getBlockByNumber response that is not in the header
+type rpcBlock struct {
+	Hash         common.Hash          `json:"hash"`
+	Transactions []*types.Transaction `json:"transactions"`
+	UncleHashes  []common.Hash        `json:"uncles"`
+	Withdrawals  []*types.Withdrawal  `json:"withdrawals,omitempty"`
+}
+
+// invalidRequestCode is the JSON-RPC error code of requests the server refuses, like batches above
+// its size limit
+const invalidRequestCode = -32600
+
+// maxBatchFailures is the number of consecutive batch requests failing as a whole, after their
+// retries, before batching is turned off
+const maxBatchFailures = 3
+
+// defaultMaxBatchItems is the most calls sent in one batch request without a configured limit, the
+// default batch limit of geth
+const defaultMaxBatchItems = 1000
+
+// errBatchNotSent is set on the calls of a batch request that failed as a whole, for the caller to
+// make them on their own
+var errBatchNotSent = errors.New("batch request not sent")
+
+// batchCallWithRetry sends the calls as JSON-RPC batch requests of at most maxBatchItems calls.
+// Calls of a batch request that could not be sent are left with errBatchNotSent, for the caller to
+// fall back to single requests. A batch the provider refuses for its size is split into smaller
+// batches, and the limit lowered for the following ones. Batching is turned off for good once the
+// provider rejects batch requests as such, or after maxBatchFailures batches in a row failed.
+func (rpcClient *RPCClient) batchCallWithRetry(ctx context.Context, elems []ethRpc.BatchElem) {
+	for start := 0; start < len(elems); {
+		if rpcClient.noBatch.Load() {
+			setBatchError(elems[start:], errors.New("batching is off"))
+			return
+		}
+		end := min(start+int(rpcClient.maxBatchItems.Load()), len(elems))
+		batch := elems[start:end]
+		tooLarge, err := rpcClient.sendBatch(ctx, batch)
+		if tooLarge {
+			if len(batch) > 1 {
+				// Send the same calls again in smaller batches
+				rpcClient.shrinkBatches(len(batch) / 2)
+				continue
+			}
+			rpcClient.noBatch.Store(true)
+			log.Printf("RPC provider refused a batch of a single call, falling back to single requests")
+			err = errors.New("batch of a single call refused")
+		}
+		if err != nil {
+			setBatchError(batch, err)
+		}
+		start = end
+	}
+}
+
+// sendBatch sends one batch request with retries. It reports whether the provider refused the
+// batch for its size, leaving its calls without results.
+func (rpcClient *RPCClient) sendBatch(ctx context.Context, elems []ethRpc.BatchElem) (bool, error) {
+	rejected, tooLarge := false, false
+	err := rpcClient.retry(ctx, batchMethod(elems), func() error {
+		err := rpcClient.client.Client().BatchCallContext(ctx, elems)
+		switch {
+		case isBatchRejected(err):
+			rejected = true
+			return nil
+		case isBatchTooLarge(err, elems):
+			tooLarge = true
+			return nil
+		}
+		return err
+	})
+	switch {
+	case rejected:
+		rpcClient.noBatch.Store(true)
+		log.Printf("RPC provider rejected a batch request, falling back to single requests")
+		return false, errors.New("batch request rejected")
+	case tooLarge:
+		return true, nil
+	case err != nil:
+		log.Printf("Batch request failed: %v. Falling back to single requests", err)
+		if ctx.Err() == nil && rpcClient.batchFailures.Add(1) >= maxBatchFailures {
+			rpcClient.noBatch.Store(true)
+			log.Printf("%d batch requests failed in a row, falling back to single requests", maxBatchFailures)
+		}
+		return false, err
+	}
+	rpcClient.batchFailures.Store(0)
+	return false, nil
+}
+
+// shrinkBatches lowers the most calls sent in one batch request to size, at least one
+func (rpcClient *RPCClient) shrinkBatches(size int) {
+	size = max(size, 1)
+	for {
+		current := rpcClient.maxBatchItems.Load()
+		if current <= int32(size) {
+			return
+		}
+		if rpcClient.maxBatchItems.CompareAndSwap(current, int32(size)) {
+			log.Printf("RPC provider refused a batch of %d calls, sending at most %d calls per batch", current, size)
+			return
+		}
+	}
+}
+
+// setBatchError marks the calls of a batch request that failed as a whole. The error is not
+// wrapped so it is not taken for the error of a call.
+func setBatchError(elems []ethRpc.BatchElem, err error) {
+	for i := range elems {
+		elems[i].Error = fmt.Errorf("%w: %v", errBatchNotSent, err)
+	}
+}
+
+// batchMethod labels a batch request with the method of its calls, the batches sent by the
+// indexer never mix methods
+func batchMethod(elems []ethRpc.BatchElem) string {
+	if len(elems) == 0 {
+		return "batch"
+	}
+	return "batch:" + elems[0].Method
+}
+
+// isBatchRejected checks if the provider refused batch requests as such rather than failing
+// transiently or refusing a batch for its size
+func isBatchRejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr ethRpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusMethodNotAllowed || httpErr.StatusCode == http.StatusNotImplemented
+	}
+	var rpcErr ethRpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode
+}
+
+// isBatchTooLarge checks if the provider refused a batch for its size: with HTTP 413, or with a
+// single invalid request error, which is how geth reports batches above its limit. The other
+// calls are then left without a response.
+func isBatchTooLarge(err error, elems []ethRpc.BatchElem) bool {
+	var httpErr ethRpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusRequestEntityTooLarge
+	}
+	var rpcErr ethRpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == invalidRequestCode
+	}
+	if err != nil {
+		return false
+	}
+	for _, elem := range elems {
+		if errors.As(elem.Error, &rpcErr) && rpcErr.ErrorCode() == invalidRequestCode {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBlocksWithRetry retrieves several blocks with batch requests. Blocks that fail
+// within the batch, or all of them when batching is not available, are retrieved one by one.
+func (rpcClient *RPCClient) GetBlocksWithRetry(ctx context.Context, numbers []uint64) ([]*types.Block, error) {
+	blocks := make([]*types.Block, len(numbers))
+	if len(numbers) > 1 && !rpcClient.noBatch.Load() {
+		raws := make([]json.RawMessage, len(numbers))
+		elems := make([]ethRpc.BatchElem, len(numbers))
+		for i, number := range numbers {
+			elems[i] = ethRpc.BatchElem{
+				Method: "eth_getBlockByNumber",
+				Args:   []interface{}{hexutil.EncodeUint64(number), true},
+				Result: &raws[i],
+			}
+		}
+		rpcClient.batchCallWithRetry(ctx, elems)
+		for i := range elems {
+			if errors.Is(elems[i].Error, errBatchNotSent) {
+				continue
+			}
+			if elems[i].Error != nil {
+				log.Printf("Error retrieving block %d in batch: %v", numbers[i], elems[i].Error)
+				continue
+			}
+			block, err := rpcClient.parseBlock(ctx, raws[i])
+			if err != nil {
+				log.Printf("Error parsing block %d in batch: %v", numbers[i], err)
+				continue
+			}
+			blocks[i] = block
+		}
+	}
+
+	for i, number := range numbers {
+		if blocks[i] != nil {
+			continue
+		}
+		block, err := rpcClient.GetBlockWithRetry(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		blocks[i] = block
+	}
+	return blocks, nil
+}
+
+// parseBlock decodes an eth_getBlockByNumber response, retrieving the uncle headers it references
+func (rpcClient *RPCClient) parseBlock(ctx context.Context, raw json.RawMessage) (*types.Block, error) {
+	var head *types.Header
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+	// When the block is not found, the API returns JSON null
+	if head == nil {
+		return nil, ethereum.NotFound
+	}
+
+	var body rpcBlock
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, err
+	}
+
+	var uncles []*types.Header
+	if len(body.UncleHashes) > 0 {
+		uncles = make([]*types.Header, len(body.UncleHashes))
+		elems := make([]ethRpc.BatchElem, len(body.UncleHashes))
+		for i := range elems {
+			elems[i] = ethRpc.BatchElem{
+				Method: "eth_getUncleByBlockHashAndIndex",
+				Args:   []interface{}{body.Hash, hexutil.EncodeUint64(uint64(i))},
+				Result: &uncles[i],
+			}
+		}
+		start := time.Now()
+		err := rpcClient.client.Client().BatchCallContext(ctx, elems)
+		observeRPC(batchMethod(elems), rpcClient.endpoint(), start, err)
+		if err != nil {
+			return nil, err
+		}
+		for i := range elems {
+			if elems[i].Error != nil {
+				return nil, elems[i].Error
+			}
+			if uncles[i] == nil {
+				return nil, fmt.Errorf("got null header for uncle %d of block %s", i, body.Hash.Hex())
+			}
+		}
+	}
+
+	return types.NewBlockWithHeader(head).WithBody(types.Body{
+		Transactions: body.Transactions,
+		Uncles:       uncles,
+		Withdrawals:  body.Withdrawals,
+	}), nil
+}
+
+// GetBlocksReceiptsWithRetry retrieves the receipts of several blocks with batches of
+// eth_getBlockReceipts calls, falling back to GetReceiptsWithRetry for each block.
+func (rpcClient *RPCClient) GetBlocksReceiptsWithRetry(ctx context.Context, blocks []*types.Block) ([][]*types.Receipt, error) {
+	receipts := make([][]*types.Receipt, len(blocks))
+	fetched := make([]bool, len(blocks))
+	if len(blocks) > 1 && !rpcClient.noBatch.Load() && !rpcClient.noBlockReceipts.Load() {
+		elems := make([]ethRpc.BatchElem, len(blocks))
+		for i, block := range blocks {
+			elems[i] = ethRpc.BatchElem{
+				Method: "eth_getBlockReceipts",
+				Args:   []interface{}{ethRpc.BlockNumberOrHashWithHash(block.Hash(), false)},
+				Result: &receipts[i],
+			}
+		}
+		rpcClient.batchCallWithRetry(ctx, elems)
+		for i := range elems {
+			if isMethodNotFound(elems[i].Error) {
+				rpcClient.noBlockReceipts.Store(true)
+			}
+			if elems[i].Error != nil {
+				continue
+			}
+			// A null result or missing receipts are retrieved again on their own
+			if err := checkReceipts(blocks[i], receipts[i]); err != nil {
+				log.Printf("Error retrieving receipts of block %d in batch: %v", blocks[i].NumberU64(), err)
+				continue
+			}
+			fetched[i] = true
+		}
+	}
+
+	for i, block := range blocks {
+		if fetched[i] {
+			continue
+		}
+		blockReceipts, err := rpcClient.GetReceiptsWithRetry(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		receipts[i] = blockReceipts
+	}
+	return receipts, nil
+}
+
+// checkReceipts verifies that there is a receipt for every transaction of a block. Nodes that do
+// not have the block yet return null, even for blocks without transactions.
+func checkReceipts(block *types.Block, receipts []*types.Receipt) error {
+	if receipts == nil {
+		return fmt.Errorf("got null receipts for block %s", block.Hash().Hex())
+	}
+	if len(receipts) != block.Transactions().Len() {
+		return fmt.Errorf("got %d receipts for %d transactions of block %s", len(receipts), block.Transactions().Len(), block.Hash().Hex())
+	}
+	return nil
+}
+
+// GetBalancesWithRetry retrieves several account balances with batch requests, falling
+// back to one request per balance when batching is not available.
+func (rpcClient *RPCClient) GetBalancesWithRetry(ctx context.Context, queries []BalanceQuery) ([]*big.Int, error) {
+	balances := make([]*big.Int, len(queries))
+	if len(queries) > 1 && !rpcClient.noBatch.Load() {
+		results := make([]hexutil.Big, len(queries))
+		elems := make([]ethRpc.BatchElem, len(queries))
+		for i, query := range queries {
+			elems[i] = ethRpc.BatchElem{
+				Method: "eth_getBalance",
+				Args:   []interface{}{query.Account, toBlockNumArg(query.BlockNumber)},
+				Result: &results[i],
+			}
+		}
+		rpcClient.batchCallWithRetry(ctx, elems)
+		for i := range elems {
+			if elems[i].Error == nil {
+				balances[i] = results[i].ToInt()
+			}
+		}
+	}
+
+	for i, query := range queries {
+		if balances[i] != nil {
+			continue
+		}
+		balance, err := rpcClient.GetBalanceWithRetry(ctx, query.Account, query.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		balances[i] = balance
+	}
+	return balances, nil
+}
+
+// toBlockNumArg encodes a block number as a JSON-RPC argument, nil meaning the latest block
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
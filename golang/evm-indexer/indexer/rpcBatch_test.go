@@ -0,0 +1,246 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethRpc "github.com/ethereum/go-ethereum/rpc"
+	coreData "github.com/synkube/app/core/data"
+)
+
+// fakeEth serves eth_getBalance with the address as balance, failing once for the accounts in
+// failOnce, and the headers of blocks as blocks without transactions. With release set, blocks by
+// number are only served once it is closed, their numbers are sent to held while they wait.
+type fakeEth struct {
+	mutex    sync.Mutex
+	failOnce map[common.Address]bool
+	blocks   map[common.Hash]*types.Header
+	byNumber map[uint64]*types.Header
+	held     chan uint64
+	release  chan struct{}
+}
+
+func (s *fakeEth) GetBalance(account common.Address, block string) (*hexutil.Big, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failOnce[account] {
+		delete(s.failOnce, account)
+		return nil, errors.New("header not found")
+	}
+	return (*hexutil.Big)(new(big.Int).SetBytes(account.Bytes())), nil
+}
+
+func (s *fakeEth) GetBlockByHash(hash common.Hash, full bool) (map[string]interface{}, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return blockJSON(s.blocks[hash])
+}
+
+func (s *fakeEth) GetBlockByNumber(number hexutil.Uint64, full bool) (map[string]interface{}, error) {
+	if s.release != nil {
+		s.held <- uint64(number)
+		<-s.release
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return blockJSON(s.byNumber[uint64(number)])
+}
+
+// GetBlockReceipts returns no receipts, the blocks have no transactions
+func (s *fakeEth) GetBlockReceipts(block ethRpc.BlockNumberOrHash) ([]interface{}, error) {
+	return []interface{}{}, nil
+}
+
+// blockJSON encodes a header as a block without transactions, nil for a missing block
+func blockJSON(header *types.Header) (map[string]interface{}, error) {
+	if header == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	var block map[string]interface{}
+	if err := json.Unmarshal(raw, &block); err != nil {
+		return nil, err
+	}
+	block["transactions"] = []interface{}{}
+	block["uncles"] = []interface{}{}
+	return block, nil
+}
+
+// GetCode returns no code, like for a contract that destroyed itself
+func (s *fakeEth) GetCode(account common.Address, block string) (hexutil.Bytes, error) {
+	return hexutil.Bytes{}, nil
+}
+
+// fakeRPC is a JSON-RPC server recording the size of the batch requests it receives, single
+// requests are recorded with size 0
+type fakeRPC struct {
+	eth           *fakeEth
+	server        *ethRpc.Server
+	rejectBatches bool // Answers batch requests with 405 like providers without batch support
+
+	mutex    sync.Mutex
+	requests []int
+}
+
+func newFakeRPC(t *testing.T, batchLimit int) *fakeRPC {
+	t.Helper()
+	eth := &fakeEth{
+		failOnce: make(map[common.Address]bool),
+		blocks:   make(map[common.Hash]*types.Header),
+		byNumber: make(map[uint64]*types.Header),
+	}
+	rpc := &fakeRPC{eth: eth, server: ethRpc.NewServer()}
+	if err := rpc.server.RegisterName("eth", rpc.eth); err != nil {
+		t.Fatal(err)
+	}
+	if batchLimit > 0 {
+		rpc.server.SetBatchLimits(batchLimit, 1<<20)
+	}
+	t.Cleanup(rpc.server.Stop)
+	return rpc
+}
+
+func (rpc *fakeRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	size := 0
+	var batch []json.RawMessage
+	if json.Unmarshal(body, &batch) == nil {
+		size = len(batch)
+	}
+	rpc.mutex.Lock()
+	rpc.requests = append(rpc.requests, size)
+	rpc.mutex.Unlock()
+
+	if size > 0 && rpc.rejectBatches {
+		http.Error(w, "batch requests are not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	rpc.server.ServeHTTP(w, r)
+}
+
+// takeRequests returns the sizes of the requests received since the last call
+func (rpc *fakeRPC) takeRequests() []int {
+	rpc.mutex.Lock()
+	defer rpc.mutex.Unlock()
+	requests := rpc.requests
+	rpc.requests = nil
+	return requests
+}
+
+// newFakeRPCClient connects an RPCClient to rpc
+func newFakeRPCClient(t *testing.T, rpc *fakeRPC, maxBatchItems int) *RPCClient {
+	t.Helper()
+	server := httptest.NewServer(rpc)
+	t.Cleanup(server.Close)
+	client, err := NewRPCClient(context.Background(), []coreData.RPC{{URL: server.URL}}, 1, maxBatchItems)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// balanceQueries returns queries of n accounts, whose balances are their position from 1
+func balanceQueries(n int) []BalanceQuery {
+	queries := make([]BalanceQuery, n)
+	for i := range queries {
+		queries[i] = BalanceQuery{Account: common.BigToAddress(big.NewInt(int64(i + 1)))}
+	}
+	return queries
+}
+
+// checkBalances fetches the balances of n accounts and checks them
+func checkBalances(t *testing.T, client *RPCClient, n int) {
+	t.Helper()
+	balances, err := client.GetBalancesWithRetry(context.Background(), balanceQueries(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, balance := range balances {
+		if balance.Int64() != int64(i+1) {
+			t.Errorf("balance %d = %s, want %d", i, balance, i+1)
+		}
+	}
+}
+
+func TestBatchesAreSplitByMaxBatchItems(t *testing.T) {
+	rpc := newFakeRPC(t, 0)
+	client := newFakeRPCClient(t, rpc, 3)
+
+	checkBalances(t, client, 7)
+	if requests := rpc.takeRequests(); !reflect.DeepEqual(requests, []int{3, 3, 1}) {
+		t.Errorf("requests of sizes %v, want batches of 3, 3 and 1", requests)
+	}
+}
+
+func TestBatchTooLargeShrinksBatches(t *testing.T) {
+	rpc := newFakeRPC(t, 4)
+	client := newFakeRPCClient(t, rpc, 10)
+
+	checkBalances(t, client, 10)
+	// geth answers batches above its limit with a single error, the calls are sent again in halves
+	if requests := rpc.takeRequests(); !reflect.DeepEqual(requests, []int{10, 5, 2, 2, 2, 2, 2}) {
+		t.Errorf("requests of sizes %v, want a batch of 10 then 5 split into batches of 2", requests)
+	}
+	if client.noBatch.Load() {
+		t.Error("batching turned off by a batch above the size limit")
+	}
+
+	checkBalances(t, client, 4)
+	if requests := rpc.takeRequests(); !reflect.DeepEqual(requests, []int{2, 2}) {
+		t.Errorf("requests of sizes %v after shrinking, want batches of 2", requests)
+	}
+}
+
+func TestBatchRejectedFallsBackToSingleRequests(t *testing.T) {
+	rpc := newFakeRPC(t, 0)
+	rpc.rejectBatches = true
+	client := newFakeRPCClient(t, rpc, 10)
+
+	checkBalances(t, client, 3)
+	if requests := rpc.takeRequests(); !reflect.DeepEqual(requests, []int{3, 0, 0, 0}) {
+		t.Errorf("requests of sizes %v, want a rejected batch then single requests", requests)
+	}
+	if !client.noBatch.Load() {
+		t.Error("batching still on after the provider rejected a batch request")
+	}
+
+	checkBalances(t, client, 2)
+	if requests := rpc.takeRequests(); !reflect.DeepEqual(requests, []int{0, 0}) {
+		t.Errorf("requests of sizes %v, want single requests only", requests)
+	}
+}
+
+func TestBatchElementErrorsAreRetriedAlone(t *testing.T) {
+	rpc := newFakeRPC(t, 0)
+	client := newFakeRPCClient(t, rpc, 10)
+	rpc.eth.failOnce[balanceQueries(3)[1].Account] = true
+
+	checkBalances(t, client, 3)
+	if requests := rpc.takeRequests(); !reflect.DeepEqual(requests, []int{3, 0}) {
+		t.Errorf("requests of sizes %v, want a batch then a single request for the failed call", requests)
+	}
+	if client.noBatch.Load() || client.batchFailures.Load() != 0 {
+		t.Error("a failing call counted as a failure of the batch request")
+	}
+}
@@ -0,0 +1,120 @@
+package indexer
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	goEthCommon "github.com/ethereum/go-ethereum/common"
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+var (
+	// Transfer(address,address,uint256) is shared by ERC-20 and ERC-721, which indexes the token ID
+	transferTopic       = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	transferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+
+	uint256ArrayType, _ = abi.NewType("uint256[]", "", nil)
+	transferBatchData   = abi.Arguments{{Type: uint256ArrayType}, {Type: uint256ArrayType}}
+)
+
+// processTokenTransfers decodes the standard token transfer events from the logs of a block.
+// Logs that match a transfer topic but do not follow the standard layout are skipped.
+func processTokenTransfers(block *goEthTypes.Block, receipts []*goEthTypes.Receipt) []*data.TokenTransfer {
+	timestamp := time.Unix(int64(block.Time()), 0)
+	transfers := make([]*data.TokenTransfer, 0)
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			decoded, err := decodeTokenTransfer(l)
+			if err != nil {
+				log.Printf("Skipping token transfer in log %d of block %d: %v", l.Index, block.NumberU64(), err)
+				continue
+			}
+			for _, transfer := range decoded {
+				transfer.Timestamp = timestamp
+				transfers = append(transfers, transfer)
+			}
+		}
+	}
+	return transfers
+}
+
+// decodeTokenTransfer decodes a single log into token transfers, returning none if it is not a transfer event
+func decodeTokenTransfer(l *goEthTypes.Log) ([]*data.TokenTransfer, error) {
+	if len(l.Topics) == 0 {
+		return nil, nil
+	}
+
+	switch l.Topics[0] {
+	case transferTopic:
+		switch {
+		case len(l.Topics) == 3 && len(l.Data) == 32:
+			return []*data.TokenTransfer{
+				newTokenTransfer(l, 0, data.StandardERC20, goEthCommon.Address{}, l.Topics[1], l.Topics[2], new(big.Int).SetBytes(l.Data), nil),
+			}, nil
+		case len(l.Topics) == 4 && len(l.Data) == 0:
+			return []*data.TokenTransfer{
+				newTokenTransfer(l, 0, data.StandardERC721, goEthCommon.Address{}, l.Topics[1], l.Topics[2], big.NewInt(1), l.Topics[3].Big()),
+			}, nil
+		}
+		return nil, fmt.Errorf("unexpected Transfer layout with %d topics and %d bytes of data", len(l.Topics), len(l.Data))
+	case transferSingleTopic:
+		if len(l.Topics) != 4 || len(l.Data) != 64 {
+			return nil, fmt.Errorf("unexpected TransferSingle layout with %d topics and %d bytes of data", len(l.Topics), len(l.Data))
+		}
+		operator := goEthCommon.BytesToAddress(l.Topics[1].Bytes())
+		tokenID := new(big.Int).SetBytes(l.Data[:32])
+		amount := new(big.Int).SetBytes(l.Data[32:])
+		return []*data.TokenTransfer{
+			newTokenTransfer(l, 0, data.StandardERC1155, operator, l.Topics[2], l.Topics[3], amount, tokenID),
+		}, nil
+	case transferBatchTopic:
+		if len(l.Topics) != 4 {
+			return nil, fmt.Errorf("unexpected TransferBatch layout with %d topics", len(l.Topics))
+		}
+		values, err := transferBatchData.Unpack(l.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack TransferBatch data: %v", err)
+		}
+		ids, amounts := values[0].([]*big.Int), values[1].([]*big.Int)
+		if len(ids) != len(amounts) {
+			return nil, fmt.Errorf("TransferBatch has %d ids and %d amounts", len(ids), len(amounts))
+		}
+		operator := goEthCommon.BytesToAddress(l.Topics[1].Bytes())
+		transfers := make([]*data.TokenTransfer, 0, len(ids))
+		for i := range ids {
+			transfers = append(transfers, newTokenTransfer(l, uint64(i), data.StandardERC1155, operator, l.Topics[2], l.Topics[3], amounts[i], ids[i]))
+		}
+		return transfers, nil
+	}
+	return nil, nil
+}
+
+// newTokenTransfer creates a TokenTransfer from a decoded log, tokenID is nil for fungible tokens
+func newTokenTransfer(l *goEthTypes.Log, batchIndex uint64, standard string, operator goEthCommon.Address, from, to goEthCommon.Hash, amount, tokenID *big.Int) *data.TokenTransfer {
+	transfer := &data.TokenTransfer{
+		ID:              fmt.Sprintf("%s-%d-%d", l.TxHash.Hex(), l.Index, batchIndex),
+		BlockHash:       l.BlockHash.Hex(),
+		BlockNumber:     l.BlockNumber,
+		TransactionHash: l.TxHash.Hex(),
+		LogIndex:        uint64(l.Index),
+		BatchIndex:      batchIndex,
+		TokenAddress:    l.Address.Hex(),
+		Standard:        standard,
+		FromAddress:     goEthCommon.BytesToAddress(from.Bytes()).Hex(),
+		ToAddress:       goEthCommon.BytesToAddress(to.Bytes()).Hex(),
+		Amount:          amount.String(),
+	}
+	if operator != (goEthCommon.Address{}) {
+		transfer.Operator = operator.Hex()
+	}
+	if tokenID != nil {
+		transfer.TokenID = tokenID.String()
+	}
+	return transfer
+}
@@ -0,0 +1,182 @@
+package indexer
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	goEthCommon "github.com/ethereum/go-ethereum/common"
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+var (
+	token    = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000a1")
+	operator = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000b1")
+	sender   = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000b2")
+	receiver = goEthCommon.HexToAddress("0x00000000000000000000000000000000000000b3")
+	txHash   = goEthCommon.HexToHash("0x01")
+)
+
+// word encodes a number as a 32 bytes ABI word
+func word(n int64) []byte {
+	return goEthCommon.LeftPadBytes(big.NewInt(n).Bytes(), 32)
+}
+
+// addressTopic encodes an address as an indexed event argument
+func addressTopic(address goEthCommon.Address) goEthCommon.Hash {
+	return goEthCommon.BytesToHash(address.Bytes())
+}
+
+// transferLog returns log 7 of the token with the given topics and data
+func transferLog(data []byte, topics ...goEthCommon.Hash) *goEthTypes.Log {
+	return &goEthTypes.Log{
+		Address:     token,
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: 5,
+		TxHash:      txHash,
+		Index:       7,
+	}
+}
+
+// wantTransfer returns the transfer from sender to receiver decoded from log 7 of the token
+func wantTransfer(batchIndex uint64, standard, operator, amount, tokenID string) *data.TokenTransfer {
+	return &data.TokenTransfer{
+		ID:              fmt.Sprintf("%s-7-%d", txHash.Hex(), batchIndex),
+		BlockHash:       goEthCommon.Hash{}.Hex(),
+		BlockNumber:     5,
+		TransactionHash: txHash.Hex(),
+		LogIndex:        7,
+		BatchIndex:      batchIndex,
+		TokenAddress:    token.Hex(),
+		Standard:        standard,
+		Operator:        operator,
+		FromAddress:     sender.Hex(),
+		ToAddress:       receiver.Hex(),
+		Amount:          amount,
+		TokenID:         tokenID,
+	}
+}
+
+func TestDecodeTokenTransfer(t *testing.T) {
+	batchData, err := transferBatchData.Pack([]*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(10), big.NewInt(20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unevenBatch, err := transferBatchData.Pack([]*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := addressTopic(sender), addressTopic(receiver)
+	single := append(word(42), word(3)...)
+
+	tests := []struct {
+		name    string
+		log     *goEthTypes.Log
+		want    []*data.TokenTransfer
+		wantErr bool
+	}{
+		{
+			name: "ERC-20 Transfer",
+			log:  transferLog(word(1000), transferTopic, from, to),
+			want: []*data.TokenTransfer{wantTransfer(0, data.StandardERC20, "", "1000", "")},
+		},
+		{
+			name: "ERC-721 Transfer",
+			log:  transferLog(nil, transferTopic, from, to, goEthCommon.BigToHash(big.NewInt(42))),
+			want: []*data.TokenTransfer{wantTransfer(0, data.StandardERC721, "", "1", "42")},
+		},
+		{
+			name: "ERC-1155 TransferSingle",
+			log:  transferLog(single, transferSingleTopic, addressTopic(operator), from, to),
+			want: []*data.TokenTransfer{wantTransfer(0, data.StandardERC1155, operator.Hex(), "3", "42")},
+		},
+		{
+			name: "ERC-1155 TransferBatch",
+			log:  transferLog(batchData, transferBatchTopic, addressTopic(operator), from, to),
+			want: []*data.TokenTransfer{
+				wantTransfer(0, data.StandardERC1155, operator.Hex(), "10", "1"),
+				wantTransfer(1, data.StandardERC1155, operator.Hex(), "20", "2"),
+			},
+		},
+		{
+			name: "other event",
+			log:  transferLog(word(1), goEthCommon.HexToHash("0x1234"), from, to),
+		},
+		{
+			name: "anonymous event",
+			log:  transferLog(word(1)),
+		},
+		{
+			name:    "Transfer with two topics",
+			log:     transferLog(word(1000), transferTopic, from),
+			wantErr: true,
+		},
+		{
+			name:    "ERC-20 Transfer with an indexed amount",
+			log:     transferLog(word(1000), transferTopic, from, to, goEthCommon.BigToHash(big.NewInt(1000))),
+			wantErr: true,
+		},
+		{
+			name:    "TransferSingle with three topics",
+			log:     transferLog(single, transferSingleTopic, addressTopic(operator), from),
+			wantErr: true,
+		},
+		{
+			name:    "TransferSingle with a short data",
+			log:     transferLog(word(42), transferSingleTopic, addressTopic(operator), from, to),
+			wantErr: true,
+		},
+		{
+			name:    "TransferBatch with five topics",
+			log:     transferLog(batchData, transferBatchTopic, addressTopic(operator), from, to, to),
+			wantErr: true,
+		},
+		{
+			name:    "TransferBatch with more ids than amounts",
+			log:     transferLog(unevenBatch, transferBatchTopic, addressTopic(operator), from, to),
+			wantErr: true,
+		},
+		{
+			name:    "TransferBatch with truncated data",
+			log:     transferLog(batchData[:len(batchData)-32], transferBatchTopic, addressTopic(operator), from, to),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transfers, err := decodeTokenTransfer(test.log)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %t", err, test.wantErr)
+			}
+			if len(transfers) != len(test.want) {
+				t.Fatalf("%d transfers, want %d", len(transfers), len(test.want))
+			}
+			for i := range transfers {
+				if !reflect.DeepEqual(transfers[i], test.want[i]) {
+					t.Errorf("transfer %d = %+v, want %+v", i, transfers[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProcessTokenTransfersSkipsMalformedLogs(t *testing.T) {
+	block, _, receipts := testBlock(&receiver)
+	from, to := addressTopic(sender), addressTopic(receiver)
+	receipts[0].Logs = []*goEthTypes.Log{
+		transferLog(word(1000), transferTopic, from),
+		transferLog(word(1000), transferTopic, from, to),
+	}
+
+	transfers := processTokenTransfers(block, receipts)
+	if len(transfers) != 1 || transfers[0].Amount != "1000" {
+		t.Fatalf("transfers = %+v, want the well formed transfer of 1000", transfers)
+	}
+	if want := time.Unix(int64(block.Time()), 0); !transfers[0].Timestamp.Equal(want) {
+		t.Errorf("timestamp = %s, want the block time %s", transfers[0].Timestamp, want)
+	}
+}
@@ -0,0 +1,150 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	goEthCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	goEthTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/synkube/app/evm-indexer/data"
+)
+
+// CallFrame is a call of the callTracer output, with the calls it made nested in it
+type CallFrame struct {
+	Type    string               `json:"type"`
+	From    goEthCommon.Address  `json:"from"`
+	To      *goEthCommon.Address `json:"to"`
+	Value   *hexutil.Big         `json:"value"`
+	Gas     hexutil.Uint64       `json:"gas"`
+	GasUsed hexutil.Uint64       `json:"gasUsed"`
+	Error   string               `json:"error"`
+	Output  hexutil.Bytes        `json:"output"` // Runtime code of the contract for CREATE frames
+	Calls   []CallFrame          `json:"calls"`
+}
+
+// TxTrace is the trace of a transaction in a debug_traceBlockByNumber response. Older nodes
+// leave out the transaction hash, traces are in transaction order either way.
+type TxTrace struct {
+	TxHash goEthCommon.Hash `json:"txHash"`
+	Result CallFrame        `json:"result"`
+}
+
+// TraceBlockWithRetry traces the calls of every transaction in a block with the callTracer. It
+// returns nil without an error once the node turned out not to support debug_traceBlockByNumber.
+func (rpcClient *RPCClient) TraceBlockWithRetry(ctx context.Context, number uint64) ([]TxTrace, error) {
+	if rpcClient.noTrace.Load() {
+		return nil, nil
+	}
+
+	var traces []TxTrace
+	err := rpcClient.retry(ctx, "debug_traceBlockByNumber", func() error {
+		err := rpcClient.client.Client().CallContext(ctx, &traces, "debug_traceBlockByNumber",
+			hexutil.EncodeUint64(number), map[string]interface{}{"tracer": "callTracer"})
+		if isMethodNotFound(err) {
+			rpcClient.noTrace.Store(true)
+			log.Printf("debug_traceBlockByNumber is not supported, internal transactions are not indexed")
+			traces = nil
+			return nil
+		}
+		return err
+	})
+	return traces, err
+}
+
+// deployment is a contract deployed by a CREATE or CREATE2 frame that was not reverted, by the frame
+// itself or by one of the frames it was called from
+type deployment struct {
+	address  goEthCommon.Address
+	creator  string
+	txHash   string
+	code     []byte // Runtime code returned by the frame, nil when the trace has no output
+	internal bool   // Deployed by a contract rather than by the transaction
+}
+
+// processInternalTransactions flattens the call trees of a block into internal transactions. The
+// top level call of each tree is the transaction itself and is left out. It also returns the
+// contracts the traces deployed.
+func processInternalTransactions(block *goEthTypes.Block, traces []TxTrace) ([]*data.InternalTransaction, []deployment, error) {
+	internalTxs := make([]*data.InternalTransaction, 0)
+	deployments := make([]deployment, 0)
+	if traces == nil {
+		return internalTxs, deployments, nil
+	}
+
+	txs := block.Transactions()
+	if len(traces) != len(txs) {
+		return nil, nil, fmt.Errorf("got %d traces for %d transactions", len(traces), len(txs))
+	}
+
+	timestamp := time.Unix(int64(block.Time()), 0)
+	for i, trace := range traces {
+		txHash := trace.TxHash
+		if txHash == (goEthCommon.Hash{}) {
+			txHash = txs[i].Hash()
+		}
+		addDeployment := func(frame CallFrame, internal bool) {
+			if createTypes[frame.Type] && frame.To != nil {
+				deployments = append(deployments, deployment{
+					address:  *frame.To,
+					creator:  frame.From.Hex(),
+					txHash:   txHash.Hex(),
+					code:     frame.Output,
+					internal: internal,
+				})
+			}
+		}
+
+		var traceIndex uint64
+		// A reverted frame undoes the calls it made, even those that succeeded on their own
+		var flatten func(frames []CallFrame, depth uint64, ancestorReverted bool)
+		flatten = func(frames []CallFrame, depth uint64, ancestorReverted bool) {
+			for _, frame := range frames {
+				internalTxs = append(internalTxs, newInternalTransaction(block, txHash, traceIndex, depth, frame, timestamp))
+				traceIndex++
+				reverted := ancestorReverted || frame.Error != ""
+				if !reverted {
+					addDeployment(frame, true)
+				}
+				flatten(frame.Calls, depth+1, reverted)
+			}
+		}
+		if trace.Result.Error == "" {
+			addDeployment(trace.Result, false)
+		}
+		flatten(trace.Result.Calls, 1, trace.Result.Error != "")
+	}
+	return internalTxs, deployments, nil
+}
+
+// newInternalTransaction creates an InternalTransaction from a call of the trace of a transaction
+func newInternalTransaction(block *goEthTypes.Block, txHash goEthCommon.Hash, traceIndex, depth uint64, frame CallFrame, timestamp time.Time) *data.InternalTransaction {
+	var to *string
+	if frame.To != nil {
+		address := frame.To.Hex()
+		to = &address
+	}
+	value := "0"
+	if frame.Value != nil {
+		value = frame.Value.ToInt().String()
+	}
+
+	return &data.InternalTransaction{
+		ID:              fmt.Sprintf("%s-%d", txHash.Hex(), traceIndex),
+		BlockHash:       block.Hash().Hex(),
+		BlockNumber:     block.NumberU64(),
+		TransactionHash: txHash.Hex(),
+		TraceIndex:      traceIndex,
+		Type:            frame.Type,
+		FromAddress:     frame.From.Hex(),
+		ToAddress:       to,
+		Value:           value,
+		Gas:             uint64(frame.Gas),
+		GasUsed:         uint64(frame.GasUsed),
+		Depth:           depth,
+		Error:           frame.Error,
+		Timestamp:       timestamp,
+	}
+}
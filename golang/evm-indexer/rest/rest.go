@@ -0,0 +1,198 @@
+package rest
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/synkube/app/evm-indexer/data"
+	"gorm.io/gorm"
+)
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+var bootTime = time.Now()
+
+// handlers serve the REST API from a BlockchainDataStore
+type handlers struct {
+	bds *data.BlockchainDataStore
+}
+
+// transactionPage is a page of transactions, nextCursor is empty on the last page
+type transactionPage struct {
+	Items      []*data.Transaction `json:"items"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+	TotalCount int64               `json:"totalCount"`
+}
+
+// status is the indexing progress of every chain
+type status struct {
+	Chains []*data.IndexerState `json:"chains"`
+}
+
+// RegisterRoutes adds the /v1 REST routes and their OpenAPI document to r
+func RegisterRoutes(r gin.IRouter, bds *data.BlockchainDataStore) {
+	h := &handlers{bds: bds}
+	v1 := r.Group("/v1")
+	v1.GET("/blocks/:number", h.getBlock)
+	v1.GET("/tx/:hash", h.getTransaction)
+	v1.GET("/address/:addr/txs", h.listAddressTransactions)
+	v1.GET("/status", h.getStatus)
+	v1.GET("/openapi.yaml", func(c *gin.Context) {
+		c.Header("Content-Type", "application/yaml")
+		http.ServeContent(c.Writer, c.Request, "openapi.yaml", bootTime, bytes.NewReader(openAPI))
+	})
+}
+
+func (h *handlers) getBlock(c *gin.Context) {
+	bds, ok := h.store(c)
+	if !ok {
+		return
+	}
+	number, err := strconv.ParseUint(c.Param("number"), 10, 64)
+	if err != nil {
+		abort(c, http.StatusBadRequest, "invalid block number %q", c.Param("number"))
+		return
+	}
+	block, err := bds.GetBlockByNumber(number)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if block == nil {
+		abort(c, http.StatusNotFound, "block %d not found", number)
+		return
+	}
+	c.JSON(http.StatusOK, block)
+}
+
+func (h *handlers) getTransaction(c *gin.Context) {
+	bds, ok := h.store(c)
+	if !ok {
+		return
+	}
+	tx, err := bds.GetTransactionByID(c.Param("hash"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tx)
+}
+
+func (h *handlers) listAddressTransactions(c *gin.Context) {
+	bds, ok := h.store(c)
+	if !ok {
+		return
+	}
+	address := c.Param("addr")
+	if !common.IsHexAddress(address) {
+		abort(c, http.StatusBadRequest, "invalid address %q", address)
+		return
+	}
+	filter := data.TransactionFilter{Address: address}
+	if filter.FromBlock, ok = optionalUint(c, "fromBlock"); !ok {
+		return
+	}
+	if filter.ToBlock, ok = optionalUint(c, "toBlock"); !ok {
+		return
+	}
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			abort(c, http.StatusBadRequest, "invalid limit %q", value)
+			return
+		}
+	}
+	order := data.TransactionOrder{Field: data.TransactionOrderBlock}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		order.Desc = true
+	default:
+		abort(c, http.StatusBadRequest, "invalid order %q, expected asc or desc", c.Query("order"))
+		return
+	}
+
+	page, err := bds.GetTransactions(filter, order, limit, c.Query("cursor"))
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	result := transactionPage{Items: make([]*data.Transaction, 0, len(page.Edges)), TotalCount: page.TotalCount}
+	for _, edge := range page.Edges {
+		result.Items = append(result.Items, edge.Node)
+	}
+	if page.HasNextPage {
+		result.NextCursor = page.EndCursor()
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *handlers) getStatus(c *gin.Context) {
+	bds, ok := h.store(c)
+	if !ok {
+		return
+	}
+	states, err := bds.GetIndexerStates()
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status{Chains: states})
+}
+
+// store returns the data store limited to the chainId query parameter, or spanning every chain
+// without one
+func (h *handlers) store(c *gin.Context) (*data.BlockchainDataStore, bool) {
+	chainID, ok := optionalUint(c, "chainId")
+	if !ok {
+		return nil, false
+	}
+	if chainID == nil {
+		return h.bds, true
+	}
+	return h.bds.ForChain(*chainID), true
+}
+
+// optionalUint parses an optional numeric query parameter, responding with an error if it is invalid
+func optionalUint(c *gin.Context, name string) (*uint64, bool) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return nil, true
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		abort(c, http.StatusBadRequest, "invalid %s %q", name, value)
+		return nil, false
+	}
+	return &number, true
+}
+
+// abort responds with a JSON error
+func abort(c *gin.Context, code int, format string, args ...interface{}) {
+	c.AbortWithStatusJSON(code, gin.H{"error": fmt.Sprintf(format, args...)})
+}
+
+// abortWithError responds with the JSON error matching a data store error
+func abortWithError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		abort(c, http.StatusNotFound, "not found")
+	case errors.Is(err, data.ErrInvalidCursor):
+		abort(c, http.StatusBadRequest, "%v", err)
+	case errors.Is(err, data.ErrChainRequired):
+		abort(c, http.StatusBadRequest, "chainId is required when several chains are indexed")
+	default:
+		log.Printf("Error serving %s: %v", c.Request.URL.Path, err)
+		abort(c, http.StatusInternalServerError, "internal error")
+	}
+}